@@ -1,41 +1,208 @@
 package main
 
 import (
+	"context"
+	"crypto/tls"
+	"flag"
 	"fmt"
-	"log"
+	"log/slog"
 	"net/http"
 	"os"
+	"os/signal"
 	"path/filepath"
 	"strings"
+	"sync"
+	"syscall"
 
+	"github.com/svg-web-elements/internal/certs"
+	"github.com/svg-web-elements/internal/config"
 	"github.com/svg-web-elements/internal/handlers"
+	"github.com/svg-web-elements/internal/logging"
+	"github.com/svg-web-elements/internal/svg"
+	"github.com/svg-web-elements/static"
 )
 
 func main() {
-	// Determine base path for static files
-	baseDir := getBaseDir()
-	svgDir := filepath.Join(baseDir, "static", "svg")
+	configFile := flag.String("config", os.Getenv("CONFIG_FILE"), "YAML configuration `file`, overridden by environment variables")
+	printConfig := flag.Bool("print-config", false, "print the effective configuration with secrets redacted and exit")
+	flag.Parse()
 
-	// Create our SVG handler
-	svgHandler := handlers.NewSVGHandler(svgDir)
+	cfg, err := config.Load(*configFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading configuration: %v\n", err)
+		os.Exit(1)
+	}
+	if *printConfig {
+		if err := cfg.Write(os.Stdout); err != nil {
+			fmt.Fprintf(os.Stderr, "Error printing configuration: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	// Log as text or JSON; the standard logger goes through it too
+	logger, err := logging.New(os.Stderr, cfg.Log.Level, cfg.Log.Format)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid logging configuration: %v\n", err)
+		os.Exit(1)
+	}
+	slog.SetDefault(logger)
+	if *configFile != "" {
+		slog.Info("Loaded configuration", "file", *configFile)
+	}
+
+	// Shut down on SIGTERM or Ctrl-C
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	svgDir := cfg.TemplateDir()
+
+	// Create our SVG processor and handler. Templates in the SVG directory
+	// override the stock templates compiled into the binary.
+	processor := svg.NewProcessorFS(svg.OverlayFS(os.DirFS(svgDir), static.Templates()))
+	processor.BasePath = svgDir
+	processor.AssetsPath = cfg.Paths.Assets
+	// Cap what a single render may ask for
+	processor.Limits = svg.RenderLimits{
+		MaxDimension:   cfg.Limits.MaxDimension,
+		MaxTextLength:  cfg.Limits.MaxTextLength,
+		MaxParams:      cfg.Limits.MaxParams,
+		MaxOutputBytes: cfg.Limits.MaxOutputBytes,
+	}
+	if len(cfg.Images.AllowedHosts) > 0 {
+		processor.RemoteImages = &svg.RemoteImagePolicy{
+			AllowedHosts: cfg.Images.AllowedHosts,
+			MaxBytes:     cfg.Images.MaxBytes,
+			Timeout:      cfg.Images.Timeout,
+		}
+	}
+	// Keep every revision of the templates so URLs can pin a version
+	cacheDir := cfg.Paths.Cache
+	processor.Revisions = svg.NewRevisionStore(filepath.Join(cacheDir, "revisions"))
+	presets, err := svg.OpenPresetStore(filepath.Join(cacheDir, "presets.json"))
+	if err != nil {
+		fatal("Error loading presets", "error", err)
+	}
+	presets.MaxPresets = cfg.Limits.MaxPresets
+	presets.MaxQueryBytes = cfg.Limits.MaxPresetQueryBytes
+	// Keep recent renders in memory, dropped whenever templates change
+	if cfg.Render.CacheBytes > 0 {
+		processor.Cache = svg.NewRenderCache(cfg.Render.CacheBytes)
+	}
+	svgHandler := handlers.NewSVGHandler(processor)
+	svgHandler.Presets = presets
+
+	// Pick up templates added or changed on disk
+	registry := svgHandler.Registry()
+	serviceMetrics := handlers.NewMetrics(registry, processor.Cache)
+	processor.Observer = serviceMetrics
+	var watcher sync.WaitGroup
+	if cfg.Templates.ReloadInterval > 0 {
+		watcher.Add(1)
+		go func() {
+			defer watcher.Done()
+			registry.Watch(ctx, cfg.Templates.ReloadInterval)
+		}()
+	}
 
 	// Setup routes
-	http.Handle("/ui/", http.StripPrefix("/ui/", svgHandler))
-	http.HandleFunc("/list", svgHandler.ListSVGsHandler)
+	// Limit how often each client may render, attributing requests from
+	// trusted proxies to the client they forward for
+	trustedProxies, err := handlers.ParseNetworks(cfg.Server.TrustedProxies)
+	if err != nil {
+		fatal("Invalid trusted proxies", "error", err)
+	}
+	limit := func(handler http.Handler) http.Handler {
+		return handler
+	}
+	if cfg.Limits.Rate > 0 {
+		limiter := handlers.NewRateLimiter(cfg.Limits.Rate, cfg.Limits.RateBurst, trustedProxies)
+		limit = limiter.Middleware
+		slog.Info("Rate limiting is enabled", "rate", cfg.Limits.Rate)
+	}
+	// Saving presets writes to disk, so it has a limit of its own
+	limitPresets := func(handler http.Handler) http.Handler {
+		return handler
+	}
+	if cfg.Limits.PresetRate > 0 {
+		limitPresets = handlers.NewRateLimiter(cfg.Limits.PresetRate, cfg.Limits.PresetRateBurst, trustedProxies).Middleware
+	}
+
+	// Let pages on other origins fetch SVGs to inline them and call the API
+	cors := func(handler http.Handler) http.Handler {
+		return handler
+	}
+	if len(cfg.CORS.AllowedOrigins) > 0 {
+		policy := &handlers.CORS{
+			AllowedOrigins: cfg.CORS.AllowedOrigins,
+			AllowedMethods: cfg.CORS.AllowedMethods,
+			AllowedHeaders: cfg.CORS.AllowedHeaders,
+			ExposedHeaders: cfg.CORS.ExposedHeaders,
+			MaxAge:         cfg.CORS.MaxAge,
+		}
+		cors = policy.Middleware
+		slog.Info("CORS is enabled", "origins", strings.Join(cfg.CORS.AllowedOrigins, ","))
+	}
+
+	http.Handle("/ui/", serviceMetrics.Instrument("ui", serviceMetrics.PathTemplate("/ui/"),
+		cors(limit(http.StripPrefix("/ui/", svgHandler)))))
+	http.Handle("/p/", serviceMetrics.Instrument("preset", serviceMetrics.PresetTemplate("/p/", presets),
+		cors(limit(http.StripPrefix("/p/", http.HandlerFunc(svgHandler.PresetHandler))))))
+	http.Handle("/list", cors(http.HandlerFunc(svgHandler.ListSVGsHandler)))
+
+	// With a signing secret, /ui/ only renders signed URLs and rendering
+	// through the API is reserved for admins
+	adminTokens := cfg.Auth.AdminTokens
+	var signer *svg.Signer
+	if cfg.Auth.SigningSecret != "" {
+		signer = svg.NewSigner(cfg.Auth.SigningSecret)
+		svgHandler.Signer = signer
+		slog.Info("URL signing is enabled")
+	}
+	restrictAPI := func(handler http.Handler) http.Handler {
+		if signer == nil {
+			return cors(limit(handler))
+		}
+		return cors(limit(handlers.RequireToken(adminTokens, handler)))
+	}
+
+	apiHandler := handlers.NewAPIHandler(processor)
+	apiHandler.BatchWorkers = cfg.Render.BatchWorkers
+	apiHandler.Presets = presets
+	http.Handle("/api/render/", serviceMetrics.Instrument("api_render", serviceMetrics.PathTemplate("/api/render/"),
+		restrictAPI(http.StripPrefix("/api/render/", http.HandlerFunc(apiHandler.RenderHandler)))))
+	http.Handle("/api/batch", serviceMetrics.Instrument("api_batch", nil,
+		restrictAPI(http.HandlerFunc(apiHandler.BatchHandler))))
+	http.Handle("/api/presets", serviceMetrics.Instrument("api_presets", nil,
+		restrictAPI(limitPresets(http.HandlerFunc(apiHandler.PresetsHandler)))))
+	if len(adminTokens) > 0 {
+		adminHandler := handlers.NewAdminHandler(processor, registry)
+		http.Handle("/admin/templates/", serviceMetrics.Instrument("admin_templates", nil,
+			handlers.RequireToken(adminTokens, http.StripPrefix("/admin/templates/", adminHandler))))
+		presetAdminHandler := handlers.NewPresetAdminHandler(presets)
+		http.Handle("/admin/presets/", handlers.RequireToken(adminTokens, http.StripPrefix("/admin/presets/", presetAdminHandler)))
+		if signer != nil {
+			signHandler := handlers.NewSignHandler(signer)
+			signHandler.Limits = processor.Limits
+			http.Handle("/admin/sign", handlers.RequireToken(adminTokens, signHandler))
+		}
+	} else {
+		slog.Info("ADMIN_TOKENS is not set, the admin API is disabled")
+	}
+	http.Handle("/metrics", serviceMetrics.Handler())
 	http.HandleFunc("/debug", func(w http.ResponseWriter, r *http.Request) {
 		svgName := r.URL.Query().Get("svg")
 		if svgName == "" {
 			http.Error(w, "Missing svg parameter", http.StatusBadRequest)
 			return
 		}
-		
-		svgPath := filepath.Join(svgDir, svgName)
-		svgData, err := os.ReadFile(svgPath)
+
+		svgData, err := processor.Source(svgName)
 		if err != nil {
 			http.Error(w, fmt.Sprintf("Error reading SVG: %v", err), http.StatusInternalServerError)
 			return
 		}
-		
+
 		w.Header().Set("Content-Type", "text/html")
 		fmt.Fprintf(w, `<!DOCTYPE html>
 		<html>
@@ -52,7 +219,7 @@ func main() {
 		</head>
 		<body>
 			<h1>SVG Debug for %s</h1>
-			
+
 			<div class="controls">
 				<a href="/">&larr; Back to home</a>
 				<p>Use this page to understand the structure of the SVG and how to modify it with query parameters.</p>
@@ -63,7 +230,7 @@ func main() {
 					<a href="/ui/%s?width=500&height=250" target="_blank">width=500&height=250</a>
 				</p>
 			</div>
-			
+
 			<div class="grid">
 				<div>
 					<h2>SVG Preview</h2>
@@ -79,14 +246,14 @@ func main() {
 						<li><code>/ui/%s?height=200</code> (width scales proportionally)</li>
 					</ul>
 				</div>
-				
+
 				<div>
 					<h2>Text Elements</h2>
 					<div id="text-elements">Loading...</div>
-					
+
 					<h2>Color Elements</h2>
 					<div id="color-elements">Loading...</div>
-					
+
 					<h2>Scaling</h2>
 					<div class="element">
 						<strong>Original Size:</strong> <span id="original-size">Loading...</span><br>
@@ -95,28 +262,28 @@ func main() {
 					</div>
 				</div>
 			</div>
-			
+
 			<h2>Raw SVG Source</h2>
 			<pre>%s</pre>
-			
+
 			<script>
 			// Function to extract elements with IDs and fills/text content
 			function analyzeSVG() {
 				const parser = new DOMParser();
 				const svgElement = document.querySelector('svg');
 				const svgDoc = parser.parseFromString(svgElement.outerHTML, "image/svg+xml");
-				
+
 				// Get SVG dimensions and viewBox
-				document.getElementById('original-size').textContent = 
+				document.getElementById('original-size').textContent =
 					svgElement.getAttribute('width') + ' x ' + svgElement.getAttribute('height');
-				document.getElementById('viewbox').textContent = 
+				document.getElementById('viewbox').textContent =
 					svgElement.getAttribute('viewBox') || 'Not specified';
-				
+
 				// Find all elements with IDs
 				const allElements = svgDoc.querySelectorAll('[id]');
 				let textHTML = '';
 				let colorHTML = '';
-				
+
 				// Process all elements
 				allElements.forEach(el => {
 					// Check for text elements
@@ -129,7 +296,7 @@ func main() {
 						textHTML += '<strong>Usage:</strong> <code>text.' + el.id + '=New+Text</code>';
 						textHTML += '</div>';
 					}
-					
+
 					// Check for elements with fill attributes
 					if (el.getAttribute('fill')) {
 						const fillColor = el.getAttribute('fill');
@@ -137,21 +304,21 @@ func main() {
 						colorHTML += '<strong>ID:</strong> ' + el.id + '<br>';
 						colorHTML += '<strong>Element Type:</strong> ' + el.tagName + '<br>';
 						colorHTML += '<strong>Current Color:</strong> <span style="display:inline-block;width:20px;height:20px;background:' + fillColor + '"></span> ' + fillColor + '<br>';
-						colorHTML += '<strong>Usage:</strong> <code>color.' + el.id + '=%23ff0000</code> (for red)';
+						colorHTML += '<strong>Usage:</strong> <code>color.' + el.id + '=%%23ff0000</code> (for red)';
 						colorHTML += '</div>';
 					}
-					
+
 					// For elements that might accept fill but don't have it yet
-					if (!el.getAttribute('fill') && (el.tagName === 'rect' || el.tagName === 'path' || 
+					if (!el.getAttribute('fill') && (el.tagName === 'rect' || el.tagName === 'path' ||
 						el.tagName === 'circle' || el.tagName === 'polygon' || el.tagName === 'g')) {
 						colorHTML += '<div class="element">';
 						colorHTML += '<strong>ID:</strong> ' + el.id + '<br>';
 						colorHTML += '<strong>Element Type:</strong> ' + el.tagName + '<br>';
-						colorHTML += '<strong>No Fill Attribute</strong> - Can be added with: <code>color.' + el.id + '=%23ff0000</code>';
+						colorHTML += '<strong>No Fill Attribute</strong> - Can be added with: <code>color.' + el.id + '=%%23ff0000</code>';
 						colorHTML += '</div>';
 					}
 				});
-				
+
 				// Helper function to get text content including from nested tspan elements
 				function getElementTextContent(element) {
 					if (element.tagName === 'text') {
@@ -165,25 +332,25 @@ func main() {
 					// For other elements with text content
 					return element.textContent.trim() || null;
 				}
-				
+
 				document.getElementById('text-elements').innerHTML = textHTML || 'No text elements found';
 				document.getElementById('color-elements').innerHTML = colorHTML || 'No color elements found';
 			}
-			
+
 			// Run analysis when page loads
 			window.onload = analyzeSVG;
 			</script>
 		</body>
-		</html>`, svgName, svgName, svgName, svgName, svgName, string(svgData), svgName, svgName, svgName, svgName, svgName, 
-		strings.ReplaceAll(strings.ReplaceAll(strings.ReplaceAll(string(svgData), "&", "&amp;"), "<", "&lt;"), ">", "&gt;"))
+		</html>`, svgName, svgName, svgName, svgName, svgName, string(svgData), svgName, svgName, svgName, svgName, svgName,
+			strings.ReplaceAll(strings.ReplaceAll(strings.ReplaceAll(string(svgData), "&", "&amp;"), "<", "&lt;"), ">", "&gt;"))
 	})
 
-	// Add health check endpoint
-	http.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Content-Type", "application/json")
-		w.Write([]byte(`{"status":"ok"}`))
-	})
-	
+	// Liveness and readiness probes; /health is kept for older setups
+	healthHandler := handlers.NewHealthHandler(registry, svgDir, cacheDir)
+	http.HandleFunc("/health", healthHandler.Live)
+	http.HandleFunc("/healthz", healthHandler.Live)
+	http.HandleFunc("/readyz", healthHandler.Ready)
+
 	// Add a simple index page
 	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
 		if r.URL.Path != "/" {
@@ -223,43 +390,43 @@ func main() {
 		<body>
 			<h1>SVG Web Elements Service</h1>
 			<p>This service provides customizable SVG illustrations via URL parameters.</p>
-			
+
 			<h2>Available SVGs</h2>
 			<p>Check the <a href="/list">list of available SVGs</a>.</p>
-			
+
 			<h2>Usage</h2>
 			<p>You can use the SVGs in your applications by creating an image tag with the URL:</p>
 			<code>&lt;img src="https://this-service/ui/basic-auth.svg?width=400&amp;height=200&amp;text.text-title=Login" /&gt;</code>
-			
+
 			<h2>Diagnostics & Examples</h2>
 			<p>Use the <a href="/debug?svg=basic-auth.svg">SVG diagnostic tool</a> to inspect SVG elements and their IDs.</p>
-			
+
 			<div class="example">
 				<h3>Basic Example</h3>
 				<img src="/ui/basic-auth.svg" alt="Basic Auth SVG" />
 				<p>URL: <code>/ui/basic-auth.svg</code></p>
 			</div>
-			
+
 			<div class="example">
 				<h3>Modified Text</h3>
 				<img src="/ui/basic-auth.svg?text.text-title=Login&text.text-url=example.com" alt="Modified Text SVG" />
 				<p>URL: <code>/ui/basic-auth.svg?text.text-title=Login&amp;text.text-url=example.com</code></p>
 			</div>
-			
+
 			<div class="example">
 				<h3>Modified Size (Proportional Scaling)</h3>
 				<img src="/ui/basic-auth.svg?width=400&height=200" alt="Modified Size SVG" />
 				<p>URL: <code>/ui/basic-auth.svg?width=400&amp;height=200</code></p>
 				<p><small>You can specify just width or height, and the other dimension will scale proportionally.</small></p>
 			</div>
-			
+
 			<div class="example">
 				<h3>Modified Colors</h3>
 				<img src="/ui/basic-auth.svg?color.page-background=%23f0f9ff&color.prompt-background=%23ffffff&color.btn-background_2=%230ea5e9" alt="Modified Colors SVG" />
 				<p>URL: <code>/ui/basic-auth.svg?color.page-background=%23f0f9ff&amp;color.prompt-background=%23ffffff&amp;color.btn-background_2=%230ea5e9</code></p>
 				<p><small>Note: Use <code>%23</code> instead of <code>#</code> in URLs for hex colors</small></p>
 			</div>
-			
+
 			<h2>Parameters</h2>
 			<ul>
 				<li><code>width</code> - Set the SVG width (other dimension scales proportionally if height not specified)</li>
@@ -267,9 +434,13 @@ func main() {
 				<li><code>text.{element-id}</code> - Replace text in element with ID</li>
 				<li><code>color.{element-id}</code> - Change color of element with ID (use <code>%23</code> instead of <code>#</code> for hex colors)</li>
 				<li><code>url</code> - External URL to use (shown in the URL field)</li>
+				<li><code>image.{element-id}</code> - Fill a <code>rect</code> or <code>image</code> placeholder with an image from the assets directory</li>
+				<li><code>fit.{element-id}</code> - How the image fills its placeholder: <code>cover</code> (default), <code>contain</code> or <code>fill</code></li>
+				<li><code>lang</code> - Language of the translation catalog to use (defaults to the <code>Accept-Language</code> header)</li>
+				<li><code>v</code> - Render a pinned version of the template, also written as <code>/ui/basic-auth@3.svg</code></li>
 			</ul>
 			<p>Try the <a href="/debug?svg=basic-auth.svg">SVG debug tool</a> to see all available element IDs.</p>
-			
+
 			<footer>
 				<p>SVG Web Elements Service</p>
 			</footer>
@@ -279,47 +450,99 @@ func main() {
 	})
 
 	// Start the server
-	port := getEnv("PORT", "8082")
-	host := getEnv("HOST", "")
-	addr := host + ":" + port
-	
-	log.Printf("Starting server on %s...", addr)
-	log.Printf("SVG files will be served from: %s", svgDir)
-	log.Fatal(http.ListenAndServe(addr, nil))
-}
+	addr := cfg.Addr()
+	server := &http.Server{
+		Addr:              addr,
+		Handler:           handlers.AccessLog(trustedProxies, http.DefaultServeMux),
+		ReadHeaderTimeout: cfg.Server.ReadHeaderTimeout,
+		ReadTimeout:       cfg.Server.ReadTimeout,
+		WriteTimeout:      cfg.Server.WriteTimeout,
+		IdleTimeout:       cfg.Server.IdleTimeout,
+		MaxHeaderBytes:    cfg.Server.MaxHeaderBytes,
+	}
+	servers := []*http.Server{server}
+	serverErr := make(chan error, 2)
 
-// getBaseDir returns the base directory for the application
-func getBaseDir() string {
-	// Check if SVG_DIR environment variable is set
-	if svgDir := os.Getenv("SVG_DIR"); svgDir != "" {
-		return svgDir
+	if cfg.TLSEnabled() {
+		// Serve HTTPS with HTTP/2, picking up renewed certificates
+		reloader, err := certs.NewReloader(cfg.Server.TLS.CertFile, cfg.Server.TLS.KeyFile)
+		if err != nil {
+			fatal("Error loading TLS certificate", "error", err)
+		}
+		server.TLSConfig = &tls.Config{
+			MinVersion:     tls.VersionTLS12,
+			GetCertificate: reloader.GetCertificate,
+		}
+		if cfg.Server.TLS.ReloadInterval > 0 {
+			watcher.Add(1)
+			go func() {
+				defer watcher.Done()
+				reloader.Watch(ctx, cfg.Server.TLS.ReloadInterval)
+			}()
+		}
+		slog.Info("Starting server", "addr", addr, "tls", true, "templates", svgDir)
+		go func() {
+			serverErr <- server.ListenAndServeTLS("", "")
+		}()
+	} else {
+		// Internal clients may speak HTTP/2 without TLS
+		if cfg.Server.H2C {
+			var protocols http.Protocols
+			protocols.SetHTTP1(true)
+			protocols.SetUnencryptedHTTP2(true)
+			server.Protocols = &protocols
+		}
+		slog.Info("Starting server", "addr", addr, "h2c", cfg.Server.H2C, "templates", svgDir)
+		go func() {
+			serverErr <- server.ListenAndServe()
+		}()
 	}
-	
-	// Try to use working directory first
-	wd, err := os.Getwd()
-	if err == nil {
-		// Check if we're running from the cmd/server directory
-		if filepath.Base(wd) == "server" && filepath.Base(filepath.Dir(wd)) == "cmd" {
-			return filepath.Dir(filepath.Dir(wd))
+
+	if redirectAddr := cfg.Server.TLS.RedirectAddr; redirectAddr != "" {
+		redirect := &http.Server{
+			Addr:              redirectAddr,
+			Handler:           handlers.RedirectHTTPS(cfg.Server.Port),
+			ReadHeaderTimeout: cfg.Server.ReadHeaderTimeout,
+			ReadTimeout:       cfg.Server.ReadTimeout,
+			WriteTimeout:      cfg.Server.WriteTimeout,
+			IdleTimeout:       cfg.Server.IdleTimeout,
+			MaxHeaderBytes:    cfg.Server.MaxHeaderBytes,
 		}
-		return wd
+		servers = append(servers, redirect)
+		slog.Info("Redirecting HTTP to HTTPS", "addr", redirectAddr)
+		go func() {
+			serverErr <- redirect.ListenAndServe()
+		}()
 	}
-	
-	// Fallback to executable directory
-	ex, err := os.Executable()
-	if err == nil {
-		return filepath.Dir(ex)
+
+	select {
+	case err := <-serverErr:
+		fatal("Server stopped", "error", err)
+	case <-ctx.Done():
 	}
-	
-	// Last resort
-	return "."
-}
 
-// getEnv gets an environment variable or returns the default value
-func getEnv(key, defaultValue string) string {
-	value := os.Getenv(key)
-	if value == "" {
-		return defaultValue
+	// Finish in-flight requests within the grace period; a second signal
+	// stops the server right away
+	stop()
+	grace := cfg.Server.ShutdownGrace
+	slog.Info("Shutting down", "grace", grace.String())
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), grace)
+	defer cancel()
+	for _, server := range servers {
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			slog.Warn("Closing connections that did not finish in time", "addr", server.Addr, "error", err)
+			server.Close()
+		}
 	}
-	return value
-}
\ No newline at end of file
+	watcher.Wait()
+	if processor.Cache != nil {
+		processor.Cache.Purge()
+	}
+	slog.Info("Server stopped")
+}
+
+// fatal logs an error and exits
+func fatal(msg string, args ...any) {
+	slog.Error(msg, args...)
+	os.Exit(1)
+}
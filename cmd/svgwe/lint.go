@@ -0,0 +1,154 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/svg-web-elements/internal/lint"
+	"github.com/svg-web-elements/internal/svg"
+)
+
+// runLint implements "svgwe lint"
+func runLint(args []string) int {
+	fs := flag.NewFlagSet("lint", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), `Usage:
+  svgwe lint [flags] <file or directory>...
+
+Checks templates, their manifests and their JSON catalogs. Go templates
+(.svg.tmpl) are checked as they render without parameters. Problems are
+printed as file:line: severity: message. The exit status is 1 when errors
+are found (or warnings, with --strict) and 0 otherwise.
+
+Example:
+  svgwe lint static/svg
+
+Flags:
+`)
+		fs.PrintDefaults()
+	}
+	strict := fs.Bool("strict", false, "fail on warnings as well as errors")
+	fonts := fs.String("fonts", strings.Join(lint.DefaultFonts, ","), "comma separated font families templates may use, empty to allow any")
+
+	paths, err := parseArgs(fs, args)
+	if err != nil {
+		return exitUsage
+	}
+	if len(paths) == 0 {
+		fs.Usage()
+		return exitUsage
+	}
+
+	opts := lint.Options{}
+	if *fonts != "" {
+		opts.Fonts = strings.Split(*fonts, ",")
+	}
+
+	templates, err := collectTemplates(paths)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "svgwe lint: %v\n", err)
+		return exitUsage
+	}
+
+	var problems []lint.Problem
+	for _, file := range templates {
+		problems = append(problems, lintTemplate(file, opts)...)
+	}
+
+	sort.SliceStable(problems, func(i, j int) bool {
+		if problems[i].File != problems[j].File {
+			return problems[i].File < problems[j].File
+		}
+		return problems[i].Line < problems[j].Line
+	})
+
+	errorCount, warningCount := 0, 0
+	for _, problem := range problems {
+		fmt.Println(problem)
+		if problem.Severity == lint.Error {
+			errorCount++
+		} else {
+			warningCount++
+		}
+	}
+	fmt.Fprintf(os.Stderr, "%d templates, %d errors, %d warnings\n", len(templates), errorCount, warningCount)
+
+	if errorCount > 0 || (*strict && warningCount > 0) {
+		return exitFail
+	}
+	return exitOK
+}
+
+// lintTemplate checks a template along with its manifest and catalogs
+func lintTemplate(file string, opts lint.Options) []lint.Problem {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return []lint.Problem{{File: file, Line: 1, Severity: lint.Error, Rule: "read", Message: err.Error()}}
+	}
+
+	// Go templates aren't XML until they are executed, so the document they
+	// render without parameters is checked instead
+	if strings.HasSuffix(file, svg.TemplateExt) {
+		data, err = svg.ExecuteTemplate(file, data)
+		if err != nil {
+			return []lint.Problem{{File: file, Line: 1, Severity: lint.Error, Rule: "template", Message: err.Error()}}
+		}
+	}
+
+	result := lint.SVG(file, data, opts)
+	problems := result.Problems
+
+	base := strings.TrimSuffix(strings.TrimSuffix(file, svg.TemplateExt), ".svg")
+	if manifest, err := os.ReadFile(base + svg.ManifestSuffix); err == nil {
+		problems = append(problems, lint.Manifest(base+svg.ManifestSuffix, manifest, result.IDs)...)
+	}
+
+	catalogs, _ := filepath.Glob(base + ".*.json")
+	for _, catalogFile := range catalogs {
+		if strings.HasSuffix(catalogFile, svg.ManifestSuffix) {
+			continue
+		}
+		if catalog, err := os.ReadFile(catalogFile); err == nil {
+			problems = append(problems, lint.Catalog(catalogFile, catalog, result.IDs)...)
+		}
+	}
+
+	return problems
+}
+
+// collectTemplates expands directories into the templates they contain
+func collectTemplates(paths []string) ([]string, error) {
+	isTemplate := func(path string) bool {
+		return strings.HasSuffix(path, ".svg") || strings.HasSuffix(path, ".svg"+svg.TemplateExt)
+	}
+
+	var files []string
+	for _, root := range paths {
+		info, err := os.Stat(root)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			files = append(files, root)
+			continue
+		}
+		err = filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if !entry.IsDir() && isTemplate(path) {
+				files = append(files, path)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return files, nil
+}
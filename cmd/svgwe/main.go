@@ -0,0 +1,113 @@
+// Command svgwe renders SVG Web Elements templates without running the server
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"log/slog"
+	"os"
+	"strings"
+
+	"github.com/svg-web-elements/internal/logging"
+	"github.com/svg-web-elements/internal/svg"
+	"github.com/svg-web-elements/static"
+)
+
+// Exit codes
+const (
+	exitOK    = 0
+	exitFail  = 1
+	exitUsage = 2
+)
+
+// commands maps subcommand names to their implementations
+var commands = map[string]func(args []string) int{
+	"render":    runRender,
+	"prerender": runPrerender,
+	"lint":      runLint,
+	"sign":      runSign,
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(exitUsage)
+	}
+
+	run, ok := commands[os.Args[1]]
+	if !ok {
+		if os.Args[1] != "help" && os.Args[1] != "-h" && os.Args[1] != "--help" {
+			fmt.Fprintf(os.Stderr, "svgwe: unknown command %q\n\n", os.Args[1])
+		}
+		usage()
+		os.Exit(exitUsage)
+	}
+
+	// The processor logs every step at debug level, which is only useful
+	// when debugging
+	if os.Getenv("SVGWE_DEBUG") != "" {
+		logger, _ := logging.New(os.Stderr, "debug", "text")
+		slog.SetDefault(logger)
+	} else {
+		log.SetOutput(io.Discard)
+	}
+
+	os.Exit(run(os.Args[2:]))
+}
+
+// usage prints the list of commands
+func usage() {
+	fmt.Fprint(os.Stderr, `Usage: svgwe <command> [arguments]
+
+Commands:
+  render     Render a template, or every job in a manifest
+  prerender  Render /ui/ image links in Markdown and HTML files and rewrite them
+  lint       Check templates for problems
+  sign       Print a signed /ui/ URL
+
+Run "svgwe <command> -h" for the arguments of a command.
+Set SVGWE_DEBUG=1 to log processing details.
+`)
+}
+
+// parseArgs parses flags that may appear before, between or after the
+// positional arguments and returns the positional arguments
+func parseArgs(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			return positional, nil
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
+// stringList is a flag that can be given multiple times
+type stringList []string
+
+func (s *stringList) String() string {
+	return strings.Join(*s, ", ")
+}
+
+func (s *stringList) Set(value string) error {
+	*s = append(*s, value)
+	return nil
+}
+
+// newProcessor creates a processor for templates in dir, falling back to the
+// stock templates compiled into the binary
+func newProcessor(dir, assets string) *svg.Processor {
+	processor := svg.NewProcessorFS(svg.OverlayFS(os.DirFS(dir), static.Templates()))
+	// Renders on the command line are trusted and may be as large as needed
+	processor.Limits = svg.RenderLimits{}
+	processor.BasePath = dir
+	processor.AssetsPath = assets
+	return processor
+}
@@ -0,0 +1,240 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"html"
+	"io/fs"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/svg-web-elements/internal/svg"
+)
+
+// prerenderExts are the file types scanned for image links
+var prerenderExts = map[string]bool{
+	".md":       true,
+	".markdown": true,
+	".html":     true,
+	".htm":      true,
+}
+
+// runPrerender implements "svgwe prerender"
+func runPrerender(args []string) int {
+	fs := flag.NewFlagSet("prerender", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), `Usage:
+  svgwe prerender --base-url <url> [flags] <file or directory>...
+
+Finds image links to the service's /ui/ endpoint in Markdown and HTML files,
+renders them locally, writes them to the output directory with content-hash
+file names and rewrites the links to point at the rendered files.
+
+Examples:
+  svgwe prerender --base-url https://svg.example.com --out docs/assets/svg docs
+  svgwe prerender --base-url https://svg.example.com --check docs
+
+Flags:
+`)
+		fs.PrintDefaults()
+	}
+	baseURL := fs.String("base-url", "", "URL the service is reachable at, e.g. https://svg.example.com")
+	dir := fs.String("dir", "static/svg", "templates directory")
+	assets := fs.String("assets", "static/assets", "images directory for image.{element-id}")
+	out := fs.String("out", "assets/svg", "directory rendered SVGs are written to")
+	defaultCacheDir := os.Getenv("CACHE_DIR")
+	if defaultCacheDir == "" {
+		defaultCacheDir = "cache"
+	}
+	cacheDir := fs.String("cache-dir", defaultCacheDir, "the service's cache directory, whose revisions pinned links such as name@3.svg render (default $CACHE_DIR or cache)")
+	check := fs.Bool("check", false, "only check that links reference known templates and element IDs")
+
+	paths, err := parseArgs(fs, args)
+	if err != nil {
+		return exitUsage
+	}
+	if *baseURL == "" || len(paths) == 0 {
+		fs.Usage()
+		return exitUsage
+	}
+
+	files, err := collectFiles(paths)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "svgwe prerender: %v\n", err)
+		return exitFail
+	}
+
+	processor := newProcessor(*dir, *assets)
+	processor.Revisions = svg.NewRevisionStore(filepath.Join(*cacheDir, "revisions"))
+	p := &prerenderer{
+		processor: processor,
+		linkRegex: regexp.MustCompile(regexp.QuoteMeta(strings.TrimSuffix(*baseURL, "/")+"/ui/") + `[^\s)"'<>]+`),
+		outDir:    *out,
+		check:     *check,
+	}
+
+	for _, file := range files {
+		if err := p.processFile(file); err != nil {
+			fmt.Fprintf(os.Stderr, "svgwe prerender: %s: %v\n", file, err)
+			p.problems++
+		}
+	}
+
+	if *check {
+		fmt.Fprintf(os.Stderr, "checked %d links in %d files, %d problems\n", p.links, len(files), p.problems)
+	} else {
+		fmt.Fprintf(os.Stderr, "rendered %d links in %d files, %d problems\n", p.links, len(files), p.problems)
+	}
+	if p.problems > 0 {
+		return exitFail
+	}
+	return exitOK
+}
+
+// prerenderer renders and rewrites the links in a set of files
+type prerenderer struct {
+	processor *svg.Processor
+	linkRegex *regexp.Regexp
+	outDir    string
+	check     bool
+
+	links    int
+	problems int
+}
+
+// processFile renders every service link in a file and, unless checking,
+// rewrites the file to use the rendered copies
+func (p *prerenderer) processFile(file string) error {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	content := string(data)
+	isHTML := strings.HasPrefix(filepath.Ext(file), ".htm")
+
+	// The content is rebuilt link by link, so errors report the line of
+	// the failing link rather than of its first occurrence
+	var rewritten strings.Builder
+	last := 0
+	for _, loc := range p.linkRegex.FindAllStringIndex(content, -1) {
+		p.links++
+		link := content[loc[0]:loc[1]]
+		target := link
+		if isHTML {
+			target = html.UnescapeString(link)
+		}
+		rewritten.WriteString(content[last:loc[0]])
+		last = loc[1]
+
+		replacement, err := p.renderLink(file, target)
+		if err != nil {
+			line := strings.Count(content[:loc[0]], "\n") + 1
+			fmt.Fprintf(os.Stderr, "%s:%d: %s: %v\n", file, line, target, err)
+			p.problems++
+			rewritten.WriteString(link)
+			continue
+		}
+		rewritten.WriteString(replacement)
+	}
+	rewritten.WriteString(content[last:])
+
+	if p.check || rewritten.String() == content {
+		return nil
+	}
+	return os.WriteFile(file, []byte(rewritten.String()), 0o644)
+}
+
+// renderLink renders a service URL and returns the link to the rendered
+// file, relative to the file containing the link
+func (p *prerenderer) renderLink(file, link string) (string, error) {
+	u, err := url.Parse(link)
+	if err != nil {
+		return "", err
+	}
+	// The template name is everything after /ui/, including subdirectories,
+	// and may pin a version like the service's /ui/name@3.svg
+	_, pinned, _ := strings.Cut(u.Path, "/ui/")
+	svgName, version, err := svg.SplitVersion(pinned)
+	if err != nil {
+		return "", err
+	}
+
+	params, err := svg.ParseQuery(u.Query())
+	if err != nil {
+		return "", err
+	}
+	if version > 0 {
+		params.Version = version
+	}
+	data, err := p.processor.ProcessSVG(svgName, params)
+	if err != nil {
+		return "", err
+	}
+
+	// Every element ID the link targets must exist in the rendered SVG
+	ids := svg.ElementIDs(data)
+	var unknown []string
+	for _, elementID := range params.ReferencedIDs() {
+		if !ids[elementID] {
+			unknown = append(unknown, elementID)
+		}
+	}
+	if len(unknown) > 0 {
+		return "", fmt.Errorf("unknown element IDs in %s: %s", svgName, strings.Join(unknown, ", "))
+	}
+
+	if p.check {
+		return link, nil
+	}
+
+	sum := sha256.Sum256(data)
+	name := strings.TrimSuffix(path.Base(svgName), path.Ext(svgName)) + "-" + hex.EncodeToString(sum[:])[:12] + ".svg"
+	output := filepath.Join(p.outDir, name)
+	if err := os.MkdirAll(p.outDir, 0o755); err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(output, data, 0o644); err != nil {
+		return "", err
+	}
+
+	rel, err := filepath.Rel(filepath.Dir(file), output)
+	if err != nil {
+		return "", err
+	}
+	return filepath.ToSlash(rel), nil
+}
+
+// collectFiles expands directories into the Markdown and HTML files they
+// contain
+func collectFiles(paths []string) ([]string, error) {
+	var files []string
+	for _, root := range paths {
+		info, err := os.Stat(root)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			files = append(files, root)
+			continue
+		}
+		err = filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if !entry.IsDir() && prerenderExts[strings.ToLower(filepath.Ext(path))] {
+				files = append(files, path)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return files, nil
+}
@@ -0,0 +1,254 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/svg-web-elements/internal/svg"
+)
+
+// renderJob is a single render in a jobs manifest
+type renderJob struct {
+	// Template is the template name, e.g. basic-auth.svg
+	Template string `yaml:"template"`
+	// Query holds parameters as a query string
+	Query string `yaml:"query"`
+	// Params holds parameters as a map, applied after Query
+	Params map[string]string `yaml:"params"`
+	// Output is the file to write, relative to the manifest
+	Output string `yaml:"output"`
+	// Format is svg or png, defaulting to the output file's extension
+	Format string `yaml:"format"`
+}
+
+// jobsManifest is a YAML or JSON file listing render jobs
+type jobsManifest struct {
+	// Dir is the templates directory, relative to the manifest
+	Dir string `yaml:"dir"`
+	// Assets is the images directory, relative to the manifest
+	Assets string      `yaml:"assets"`
+	Jobs   []renderJob `yaml:"jobs"`
+}
+
+// jobDefaults are the flags a jobs manifest falls back to. Empty fields
+// weren't given on the command line.
+type jobDefaults struct {
+	dir    string
+	assets string
+	format string
+}
+
+// runRender implements "svgwe render"
+func runRender(args []string) int {
+	fs := flag.NewFlagSet("render", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), `Usage:
+  svgwe render <template> [query] [flags]
+  svgwe render --jobs <manifest.yaml>
+
+Examples:
+  svgwe render basic-auth.svg 'text.text-title=Login&width=400' -o out.svg
+  svgwe render basic-auth.svg -p text.text-title=Login --format png -o out.png
+  svgwe render --jobs docs/illustrations.yaml
+
+Flags:
+`)
+		fs.PrintDefaults()
+	}
+	dir := fs.String("dir", "static/svg", "templates directory")
+	assets := fs.String("assets", "static/assets", "images directory for image.{element-id}")
+	output := fs.String("o", "", "output file (default stdout)")
+	format := fs.String("format", "", "output format, svg or png (default from the output extension)")
+	jobs := fs.String("jobs", "", "YAML or JSON manifest of render jobs")
+	rasterizer := fs.String("rasterizer", strings.Join(svg.RasterizeCommand, " "), "command converting SVG on stdin to PNG on stdout")
+	var params stringList
+	fs.Var(&params, "p", "parameter as key=value, may be repeated")
+
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return exitUsage
+	}
+	svg.RasterizeCommand = strings.Fields(*rasterizer)
+
+	if *jobs != "" {
+		if len(positional) > 0 {
+			fmt.Fprintln(os.Stderr, "svgwe render: --jobs can't be combined with a template")
+			return exitUsage
+		}
+		// Outputs and parameters are given per job in the manifest, while
+		// the directories given on the command line are defaults
+		defaults := jobDefaults{format: *format}
+		var combined []string
+		fs.Visit(func(f *flag.Flag) {
+			switch f.Name {
+			case "o", "p":
+				combined = append(combined, "-"+f.Name)
+			case "dir":
+				defaults.dir = *dir
+			case "assets":
+				defaults.assets = *assets
+			}
+		})
+		if len(combined) > 0 {
+			fmt.Fprintf(os.Stderr, "svgwe render: --jobs can't be combined with %s\n", strings.Join(combined, " or "))
+			return exitUsage
+		}
+		return renderJobs(*jobs, defaults)
+	}
+
+	if len(positional) < 1 || len(positional) > 2 {
+		fs.Usage()
+		return exitUsage
+	}
+
+	query := ""
+	if len(positional) == 2 {
+		query = positional[1]
+	}
+	job := renderJob{
+		Template: positional[0],
+		Query:    query,
+		Params:   make(map[string]string),
+		Output:   *output,
+		Format:   *format,
+	}
+	for _, param := range params {
+		key, value, ok := strings.Cut(param, "=")
+		if !ok {
+			fmt.Fprintf(os.Stderr, "svgwe render: parameter %q is not key=value\n", param)
+			return exitUsage
+		}
+		job.Params[key] = value
+	}
+
+	processor := newProcessor(*dir, *assets)
+	if err := render(processor, job, ""); err != nil {
+		fmt.Fprintf(os.Stderr, "svgwe render: %v\n", err)
+		return exitFail
+	}
+	return exitOK
+}
+
+// renderJobs renders every job in a manifest, reporting all failures. The
+// manifest's dir, assets and formats take precedence over the defaults.
+func renderJobs(path string, defaults jobDefaults) int {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "svgwe render: %v\n", err)
+		return exitFail
+	}
+
+	// YAML is a superset of JSON, so one decoder handles both
+	var manifest jobsManifest
+	if err := yaml.Unmarshal(data, &manifest); err != nil {
+		fmt.Fprintf(os.Stderr, "svgwe render: invalid manifest %s: %v\n", path, err)
+		return exitFail
+	}
+
+	// Directories in the manifest are relative to it, while those given on
+	// the command line are relative to the working directory
+	baseDir := filepath.Dir(path)
+	dir := manifestPath(baseDir, manifest.Dir, defaults.dir, "static/svg")
+	assets := manifestPath(baseDir, manifest.Assets, defaults.assets, "static/assets")
+	processor := newProcessor(dir, assets)
+
+	failed := 0
+	for i, job := range manifest.Jobs {
+		if job.Output == "" {
+			fmt.Fprintf(os.Stderr, "svgwe render: job %d (%s) has no output\n", i+1, job.Template)
+			failed++
+			continue
+		}
+		if job.Format == "" {
+			job.Format = defaults.format
+		}
+		if err := render(processor, job, baseDir); err != nil {
+			fmt.Fprintf(os.Stderr, "svgwe render: job %d (%s): %v\n", i+1, job.Output, err)
+			failed++
+			continue
+		}
+		fmt.Fprintf(os.Stderr, "rendered %s\n", job.Output)
+	}
+
+	if failed > 0 {
+		fmt.Fprintf(os.Stderr, "svgwe render: %d of %d jobs failed\n", failed, len(manifest.Jobs))
+		return exitFail
+	}
+	return exitOK
+}
+
+// render renders one job and writes it to its output, or stdout when the
+// job has no output. Relative outputs are resolved against baseDir.
+func render(processor *svg.Processor, job renderJob, baseDir string) error {
+	query, err := url.ParseQuery(job.Query)
+	if err != nil {
+		return fmt.Errorf("invalid query: %w", err)
+	}
+	for key, value := range job.Params {
+		query.Set(key, value)
+	}
+
+	params, err := svg.ParseQuery(query)
+	if err != nil {
+		return err
+	}
+
+	data, err := processor.ProcessSVG(job.Template, params)
+	if err != nil {
+		return err
+	}
+
+	format := job.Format
+	if format == "" {
+		format = strings.TrimPrefix(filepath.Ext(job.Output), ".")
+	}
+	switch strings.ToLower(format) {
+	case "", "svg":
+	case "png":
+		data, err = svg.RasterizePNG(context.Background(), data)
+		if err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unsupported format %q", format)
+	}
+
+	if job.Output == "" || job.Output == "-" {
+		_, err = os.Stdout.Write(data)
+		return err
+	}
+
+	output := resolvePath(baseDir, job.Output)
+	if err := os.MkdirAll(filepath.Dir(output), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(output, data, 0o644)
+}
+
+// manifestPath returns a directory set in a manifest, or else the one given
+// on the command line, or else fallback relative to the manifest
+func manifestPath(baseDir, path, flagPath, fallback string) string {
+	switch {
+	case path != "":
+		return resolvePath(baseDir, path)
+	case flagPath != "":
+		return flagPath
+	default:
+		return resolvePath(baseDir, fallback)
+	}
+}
+
+// resolvePath resolves a path from a manifest against the manifest's directory
+func resolvePath(baseDir, path string) string {
+	if baseDir == "" || filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(baseDir, path)
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/svg-web-elements/internal/svg"
+)
+
+// runSign implements "svgwe sign"
+func runSign(args []string) int {
+	fs := flag.NewFlagSet("sign", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), `Usage:
+  svgwe sign <template> [query] [flags]
+
+Prints a /ui/ URL signed with the server's SIGNING_SECRET.
+
+Examples:
+  svgwe sign basic-auth.svg 'text.text-title=Login&width=400'
+  svgwe sign basic-auth.svg -p text.text-title=Login --expires 24h --base-url https://svg.example.com
+
+Flags:
+`)
+		fs.PrintDefaults()
+	}
+	secret := fs.String("secret", os.Getenv("SIGNING_SECRET"), "signing secret (default $SIGNING_SECRET)")
+	expires := fs.Duration("expires", 0, "how long the URL is valid, e.g. 24h (default forever)")
+	baseURL := fs.String("base-url", "", "URL the service is reachable at, e.g. https://svg.example.com")
+	var params stringList
+	fs.Var(&params, "p", "parameter as key=value, may be repeated")
+
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return exitUsage
+	}
+	if len(positional) < 1 || len(positional) > 2 {
+		fs.Usage()
+		return exitUsage
+	}
+	if *secret == "" {
+		fmt.Fprintln(os.Stderr, "svgwe sign: no secret, set SIGNING_SECRET or --secret")
+		return exitUsage
+	}
+
+	query := url.Values{}
+	if len(positional) == 2 {
+		query, err = url.ParseQuery(strings.TrimPrefix(positional[1], "?"))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "svgwe sign: invalid query: %v\n", err)
+			return exitUsage
+		}
+	}
+	for _, param := range params {
+		key, value, ok := strings.Cut(param, "=")
+		if !ok {
+			fmt.Fprintf(os.Stderr, "svgwe sign: parameter %q is not key=value\n", param)
+			return exitUsage
+		}
+		query.Set(key, value)
+	}
+
+	var expiresAt time.Time
+	if *expires > 0 {
+		expiresAt = time.Now().Add(*expires)
+	}
+	template := positional[0]
+	signed := svg.NewSigner(*secret).Sign(template, query, expiresAt)
+	fmt.Printf("%s/ui/%s?%s\n", strings.TrimSuffix(*baseURL, "/"), template, signed.Encode())
+	return exitOK
+}
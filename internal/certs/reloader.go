@@ -0,0 +1,96 @@
+// Package certs serves TLS certificates from files, reloading them when the
+// files change so renewed certificates are used without a restart
+package certs
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+)
+
+// Reloader holds the certificate loaded from a certificate and key file
+type Reloader struct {
+	certFile, keyFile string
+
+	mu      sync.RWMutex
+	cert    *tls.Certificate
+	version string
+}
+
+// NewReloader loads a certificate and its key
+func NewReloader(certFile, keyFile string) (*Reloader, error) {
+	r := &Reloader{certFile: certFile, keyFile: keyFile}
+	if err := r.Reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Reload loads the certificate again if either file changed. The current
+// certificate is kept when the files can't be loaded, e.g. while they are
+// being replaced.
+func (r *Reloader) Reload() error {
+	version, err := r.fileVersion()
+	if err != nil {
+		return err
+	}
+	r.mu.RLock()
+	unchanged := version == r.version
+	r.mu.RUnlock()
+	if unchanged {
+		return nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return fmt.Errorf("loading certificate: %w", err)
+	}
+	r.mu.Lock()
+	r.cert = &cert
+	r.version = version
+	r.mu.Unlock()
+	slog.Info("Loaded TLS certificate", "cert", r.certFile)
+	return nil
+}
+
+// fileVersion identifies the current contents of the files by their sizes
+// and modification times
+func (r *Reloader) fileVersion() (string, error) {
+	var version string
+	for _, file := range []string{r.certFile, r.keyFile} {
+		info, err := os.Stat(file)
+		if err != nil {
+			return "", err
+		}
+		version += fmt.Sprintf("%d-%d;", info.Size(), info.ModTime().UnixNano())
+	}
+	return version, nil
+}
+
+// GetCertificate returns the current certificate, for tls.Config
+func (r *Reloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.cert, nil
+}
+
+// Watch checks the files every interval until the context is done
+func (r *Reloader) Watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := r.Reload(); err != nil {
+				slog.Error("Error reloading TLS certificate", "error", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,362 @@
+// Package config loads the server configuration from an optional YAML file
+// and environment variables, which override the file
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// redacted replaces secrets when the configuration is printed
+const redacted = "[redacted]"
+
+// Config is the server configuration
+type Config struct {
+	Server    Server    `yaml:"server"`
+	Paths     Paths     `yaml:"paths"`
+	Templates Templates `yaml:"templates"`
+	Render    Render    `yaml:"render"`
+	Images    Images    `yaml:"images"`
+	Limits    Limits    `yaml:"limits"`
+	Auth      Auth      `yaml:"auth"`
+	CORS      CORS      `yaml:"cors"`
+	Log       Log       `yaml:"log"`
+}
+
+// Server configures the HTTP server
+type Server struct {
+	// Host is the interface to bind to, all interfaces when empty
+	Host string `yaml:"host"`
+	// Port is the port to listen on
+	Port int `yaml:"port"`
+	// ReadHeaderTimeout and ReadTimeout limit how long clients may take
+	// to send the request headers and the whole request
+	ReadHeaderTimeout time.Duration `yaml:"read_header_timeout"`
+	ReadTimeout       time.Duration `yaml:"read_timeout"`
+	// WriteTimeout limits how long a response may take
+	WriteTimeout time.Duration `yaml:"write_timeout"`
+	// IdleTimeout limits how long idle keep-alive connections are kept
+	IdleTimeout time.Duration `yaml:"idle_timeout"`
+	// MaxHeaderBytes is the largest request line and headers
+	MaxHeaderBytes int `yaml:"max_header_bytes"`
+	// ShutdownGrace is how long in-flight requests may take to finish
+	// when the server stops
+	ShutdownGrace time.Duration `yaml:"shutdown_grace"`
+	// TrustedProxies are the networks whose X-Forwarded-For is used
+	TrustedProxies []string `yaml:"trusted_proxies"`
+	// H2C serves HTTP/2 without TLS to clients that ask for it
+	H2C bool `yaml:"h2c"`
+	TLS TLS  `yaml:"tls"`
+}
+
+// TLS configures serving HTTPS
+type TLS struct {
+	// CertFile and KeyFile enable HTTPS with the certificate and key in
+	// them
+	CertFile string `yaml:"cert_file"`
+	KeyFile  string `yaml:"key_file"`
+	// ReloadInterval is how often the files are checked for a renewed
+	// certificate, never when zero
+	ReloadInterval time.Duration `yaml:"reload_interval"`
+	// RedirectAddr is the address of a listener redirecting HTTP requests
+	// to HTTPS, e.g. :80, none when empty
+	RedirectAddr string `yaml:"redirect_addr"`
+}
+
+// Paths configures where files are read and written
+type Paths struct {
+	// Base is the directory holding static/svg, guessed when empty
+	Base string `yaml:"base"`
+	// Assets is the directory images are embedded from, static/assets in
+	// the base directory by default
+	Assets string `yaml:"assets"`
+	// Cache is the directory revisions and presets are kept in, cache in
+	// the base directory by default
+	Cache string `yaml:"cache"`
+}
+
+// Templates configures how templates are loaded
+type Templates struct {
+	// ReloadInterval is how often the template directory is checked for
+	// changes, never when zero
+	ReloadInterval time.Duration `yaml:"reload_interval"`
+}
+
+// Render configures rendering
+type Render struct {
+	// CacheBytes is the memory for cached renders, none when zero
+	CacheBytes int `yaml:"cache_bytes"`
+	// BatchWorkers is how many jobs of a batch render at once, the number
+	// of CPUs when zero
+	BatchWorkers int `yaml:"batch_workers"`
+}
+
+// Images configures remote images
+type Images struct {
+	// AllowedHosts are the hosts images may be fetched from, none when
+	// empty
+	AllowedHosts []string `yaml:"allowed_hosts"`
+	// MaxBytes is the largest remote image that is embedded
+	MaxBytes int64 `yaml:"max_bytes"`
+	// Timeout limits fetching a remote image
+	Timeout time.Duration `yaml:"timeout"`
+}
+
+// Limits caps renders and how often clients may render
+type Limits struct {
+	MaxDimension   float64 `yaml:"max_dimension"`
+	MaxTextLength  int     `yaml:"max_text_length"`
+	MaxParams      int     `yaml:"max_params"`
+	MaxOutputBytes int     `yaml:"max_output_bytes"`
+	// Rate is the renders per second per client, unlimited when zero
+	Rate float64 `yaml:"rate"`
+	// RateBurst is the renders a client may send at once
+	RateBurst int `yaml:"rate_burst"`
+	// MaxPresets is the most presets that are stored, unlimited when zero
+	MaxPresets int `yaml:"max_presets"`
+	// MaxPresetQueryBytes is the longest query a preset may save,
+	// unlimited when zero
+	MaxPresetQueryBytes int `yaml:"max_preset_query_bytes"`
+	// PresetRate is the presets per second a client may save, unlimited
+	// when zero
+	PresetRate float64 `yaml:"preset_rate"`
+	// PresetRateBurst is the presets a client may save at once
+	PresetRateBurst int `yaml:"preset_rate_burst"`
+}
+
+// Auth configures the admin API and signed URLs
+type Auth struct {
+	// AdminTokens are the bearer tokens of the admin API, which is
+	// disabled when there are none
+	AdminTokens []string `yaml:"admin_tokens"`
+	// SigningSecret enables signed URLs
+	SigningSecret string `yaml:"signing_secret"`
+}
+
+// CORS configures which pages may fetch SVGs and call the API from
+// scripts
+type CORS struct {
+	// AllowedOrigins are the origins that may fetch responses, e.g.
+	// https://docs.example.com, https://*.example.com or *. CORS is
+	// disabled when empty.
+	AllowedOrigins []string `yaml:"allowed_origins"`
+	// AllowedMethods and AllowedHeaders are what cross-origin requests
+	// may use
+	AllowedMethods []string `yaml:"allowed_methods"`
+	AllowedHeaders []string `yaml:"allowed_headers"`
+	// ExposedHeaders are the response headers scripts may read
+	ExposedHeaders []string `yaml:"exposed_headers"`
+	// MaxAge is how long browsers may cache preflight responses
+	MaxAge time.Duration `yaml:"max_age"`
+}
+
+// Log configures logging
+type Log struct {
+	// Level is debug, info, warn or error
+	Level string `yaml:"level"`
+	// Format is text or json
+	Format string `yaml:"format"`
+}
+
+// Default returns the configuration used when nothing is configured
+func Default() Config {
+	return Config{
+		Server: Server{
+			Port:              8082,
+			ReadHeaderTimeout: 10 * time.Second,
+			ReadTimeout:       30 * time.Second,
+			WriteTimeout:      120 * time.Second,
+			IdleTimeout:       120 * time.Second,
+			MaxHeaderBytes:    http.DefaultMaxHeaderBytes,
+			ShutdownGrace:     20 * time.Second,
+			TLS:               TLS{ReloadInterval: time.Minute},
+		},
+		Templates: Templates{ReloadInterval: 10 * time.Second},
+		Render:    Render{CacheBytes: 32 << 20},
+		Images:    Images{MaxBytes: 2 << 20, Timeout: 5 * time.Second},
+		Limits: Limits{
+			MaxDimension:        4096,
+			MaxTextLength:       1000,
+			MaxParams:           100,
+			MaxOutputBytes:      5 << 20,
+			RateBurst:           20,
+			MaxPresets:          10000,
+			MaxPresetQueryBytes: 4096,
+			PresetRate:          0.2,
+			PresetRateBurst:     10,
+		},
+		CORS: CORS{
+			AllowedMethods: []string{"GET", "HEAD", "POST"},
+			AllowedHeaders: []string{"Content-Type", "Authorization", "If-None-Match"},
+			ExposedHeaders: []string{"ETag", "X-Request-ID", "Retry-After"},
+			MaxAge:         10 * time.Minute,
+		},
+		Log: Log{Level: "info", Format: "text"},
+	}
+}
+
+// Load reads the configuration file, if any, applies the environment
+// variables and checks the result. Settings missing from both keep their
+// defaults.
+func Load(file string) (Config, error) {
+	cfg := Default()
+	if file != "" {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return cfg, err
+		}
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		decoder.KnownFields(true)
+		if err := decoder.Decode(&cfg); err != nil && !errors.Is(err, io.EOF) {
+			return cfg, fmt.Errorf("%s: %w", file, err)
+		}
+	}
+	if err := cfg.applyEnv(os.Getenv); err != nil {
+		return cfg, err
+	}
+
+	if cfg.Paths.Base == "" {
+		cfg.Paths.Base = guessBaseDir()
+	}
+	if cfg.Paths.Assets == "" {
+		cfg.Paths.Assets = filepath.Join(cfg.Paths.Base, "static", "assets")
+	}
+	if cfg.Paths.Cache == "" {
+		cfg.Paths.Cache = filepath.Join(cfg.Paths.Base, "cache")
+	}
+	return cfg, cfg.Validate()
+}
+
+// TemplateDir is the directory templates are read from
+func (c Config) TemplateDir() string {
+	return filepath.Join(c.Paths.Base, "static", "svg")
+}
+
+// Addr is the address the server listens on
+func (c Config) Addr() string {
+	return c.Server.Host + ":" + strconv.Itoa(c.Server.Port)
+}
+
+// TLSEnabled reports whether the server serves HTTPS
+func (c Config) TLSEnabled() bool {
+	return c.Server.TLS.CertFile != ""
+}
+
+// Validate checks the configuration, reporting every invalid setting
+func (c Config) Validate() error {
+	var problems []string
+	check := func(ok bool, format string, args ...interface{}) {
+		if !ok {
+			problems = append(problems, fmt.Sprintf(format, args...))
+		}
+	}
+
+	check(c.Server.Port > 0 && c.Server.Port < 65536, "server.port must be between 1 and 65535")
+	for name, d := range map[string]time.Duration{
+		"server.read_header_timeout": c.Server.ReadHeaderTimeout,
+		"server.read_timeout":        c.Server.ReadTimeout,
+		"server.write_timeout":       c.Server.WriteTimeout,
+		"server.idle_timeout":        c.Server.IdleTimeout,
+		"server.shutdown_grace":      c.Server.ShutdownGrace,
+		"server.tls.reload_interval": c.Server.TLS.ReloadInterval,
+		"templates.reload_interval":  c.Templates.ReloadInterval,
+		"cors.max_age":               c.CORS.MaxAge,
+	} {
+		check(d >= 0, "%s must not be negative", name)
+	}
+	check(c.Server.MaxHeaderBytes >= 0, "server.max_header_bytes must not be negative")
+	check((c.Server.TLS.CertFile == "") == (c.Server.TLS.KeyFile == ""), "server.tls.cert_file and server.tls.key_file must be set together")
+	check(c.Server.TLS.RedirectAddr == "" || c.TLSEnabled(), "server.tls.redirect_addr requires server.tls.cert_file")
+	check(!c.Server.H2C || !c.TLSEnabled(), "server.h2c only applies without TLS, which negotiates HTTP/2 itself")
+	check(c.Render.CacheBytes >= 0, "render.cache_bytes must not be negative")
+	check(c.Render.BatchWorkers >= 0, "render.batch_workers must not be negative")
+	if len(c.Images.AllowedHosts) > 0 {
+		check(c.Images.MaxBytes > 0, "images.max_bytes must be positive")
+		check(c.Images.Timeout > 0, "images.timeout must be positive")
+	}
+	check(c.Limits.MaxDimension >= 0, "limits.max_dimension must not be negative")
+	check(c.Limits.MaxTextLength >= 0, "limits.max_text_length must not be negative")
+	check(c.Limits.MaxParams >= 0, "limits.max_params must not be negative")
+	check(c.Limits.MaxOutputBytes >= 0, "limits.max_output_bytes must not be negative")
+	check(c.Limits.Rate >= 0, "limits.rate must not be negative")
+	check(c.Limits.Rate == 0 || c.Limits.RateBurst > 0, "limits.rate_burst must be positive")
+	check(c.Limits.MaxPresets >= 0, "limits.max_presets must not be negative")
+	check(c.Limits.MaxPresetQueryBytes >= 0, "limits.max_preset_query_bytes must not be negative")
+	check(c.Limits.PresetRate >= 0, "limits.preset_rate must not be negative")
+	check(c.Limits.PresetRate == 0 || c.Limits.PresetRateBurst > 0, "limits.preset_rate_burst must be positive")
+	for _, token := range c.Auth.AdminTokens {
+		check(strings.TrimSpace(token) != "", "auth.admin_tokens must not contain empty tokens")
+	}
+	for _, origin := range c.CORS.AllowedOrigins {
+		check(origin == "*" || strings.Contains(origin, "://"), "cors.allowed_origins must be * or origins like https://example.com, not %q", origin)
+	}
+	check(len(c.CORS.AllowedOrigins) == 0 || len(c.CORS.AllowedMethods) > 0, "cors.allowed_methods must not be empty")
+	var level slog.Level
+	check(level.UnmarshalText([]byte(c.Log.Level)) == nil, "log.level must be debug, info, warn or error")
+	check(c.Log.Format == "text" || c.Log.Format == "json", "log.format must be text or json")
+
+	if len(problems) > 0 {
+		// Durations are checked from a map, so sort for a stable message
+		sort.Strings(problems)
+		return fmt.Errorf("invalid configuration:\n  %s", strings.Join(problems, "\n  "))
+	}
+	return nil
+}
+
+// Redacted returns a copy of the configuration with secrets replaced, for
+// printing
+func (c Config) Redacted() Config {
+	if len(c.Auth.AdminTokens) > 0 {
+		tokens := make([]string, len(c.Auth.AdminTokens))
+		for i := range tokens {
+			tokens[i] = redacted
+		}
+		c.Auth.AdminTokens = tokens
+	}
+	if c.Auth.SigningSecret != "" {
+		c.Auth.SigningSecret = redacted
+	}
+	return c
+}
+
+// Write prints the configuration as YAML with secrets redacted
+func (c Config) Write(w io.Writer) error {
+	data, err := yaml.Marshal(c.Redacted())
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+// guessBaseDir returns the working directory, or the repository root when
+// running from cmd/server, or the executable's directory
+func guessBaseDir() string {
+	wd, err := os.Getwd()
+	if err == nil {
+		// Check if we're running from the cmd/server directory
+		if filepath.Base(wd) == "server" && filepath.Base(filepath.Dir(wd)) == "cmd" {
+			return filepath.Dir(filepath.Dir(wd))
+		}
+		return wd
+	}
+
+	// Fallback to executable directory
+	ex, err := os.Executable()
+	if err == nil {
+		return filepath.Dir(ex)
+	}
+	return "."
+}
@@ -0,0 +1,154 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// envVar is an environment variable and the setting it overrides
+type envVar struct {
+	name string
+	set  func(value string) error
+}
+
+// envVars lists the environment variables and the settings they override
+func (c *Config) envVars() []envVar {
+	return []envVar{
+		{"HOST", stringVar(&c.Server.Host)},
+		{"PORT", intVar(&c.Server.Port)},
+		{"READ_HEADER_TIMEOUT_SECONDS", secondsVar(&c.Server.ReadHeaderTimeout)},
+		{"READ_TIMEOUT_SECONDS", secondsVar(&c.Server.ReadTimeout)},
+		{"WRITE_TIMEOUT_SECONDS", secondsVar(&c.Server.WriteTimeout)},
+		{"IDLE_TIMEOUT_SECONDS", secondsVar(&c.Server.IdleTimeout)},
+		{"MAX_HEADER_BYTES", intVar(&c.Server.MaxHeaderBytes)},
+		{"SHUTDOWN_GRACE_SECONDS", secondsVar(&c.Server.ShutdownGrace)},
+		{"TRUSTED_PROXIES", listVar(&c.Server.TrustedProxies)},
+		{"H2C", boolVar(&c.Server.H2C)},
+		{"TLS_CERT_FILE", stringVar(&c.Server.TLS.CertFile)},
+		{"TLS_KEY_FILE", stringVar(&c.Server.TLS.KeyFile)},
+		{"TLS_RELOAD_SECONDS", secondsVar(&c.Server.TLS.ReloadInterval)},
+		{"TLS_REDIRECT_ADDR", stringVar(&c.Server.TLS.RedirectAddr)},
+		{"SVG_DIR", stringVar(&c.Paths.Base)},
+		{"ASSETS_DIR", stringVar(&c.Paths.Assets)},
+		{"CACHE_DIR", stringVar(&c.Paths.Cache)},
+		{"TEMPLATE_RELOAD_SECONDS", secondsVar(&c.Templates.ReloadInterval)},
+		{"RENDER_CACHE_BYTES", intVar(&c.Render.CacheBytes)},
+		{"BATCH_WORKERS", intVar(&c.Render.BatchWorkers)},
+		{"IMAGE_HOSTS", listVar(&c.Images.AllowedHosts)},
+		{"IMAGE_MAX_BYTES", int64Var(&c.Images.MaxBytes)},
+		{"IMAGE_TIMEOUT_SECONDS", secondsVar(&c.Images.Timeout)},
+		{"MAX_DIMENSION", floatVar(&c.Limits.MaxDimension)},
+		{"MAX_TEXT_LENGTH", intVar(&c.Limits.MaxTextLength)},
+		{"MAX_PARAMS", intVar(&c.Limits.MaxParams)},
+		{"MAX_OUTPUT_BYTES", intVar(&c.Limits.MaxOutputBytes)},
+		{"RATE_LIMIT", floatVar(&c.Limits.Rate)},
+		{"RATE_LIMIT_BURST", intVar(&c.Limits.RateBurst)},
+		{"MAX_PRESETS", intVar(&c.Limits.MaxPresets)},
+		{"MAX_PRESET_QUERY_BYTES", intVar(&c.Limits.MaxPresetQueryBytes)},
+		{"PRESET_RATE_LIMIT", floatVar(&c.Limits.PresetRate)},
+		{"PRESET_RATE_LIMIT_BURST", intVar(&c.Limits.PresetRateBurst)},
+		{"ADMIN_TOKENS", listVar(&c.Auth.AdminTokens)},
+		{"SIGNING_SECRET", stringVar(&c.Auth.SigningSecret)},
+		{"CORS_ORIGINS", listVar(&c.CORS.AllowedOrigins)},
+		{"CORS_METHODS", listVar(&c.CORS.AllowedMethods)},
+		{"CORS_HEADERS", listVar(&c.CORS.AllowedHeaders)},
+		{"CORS_EXPOSED_HEADERS", listVar(&c.CORS.ExposedHeaders)},
+		{"CORS_MAX_AGE_SECONDS", secondsVar(&c.CORS.MaxAge)},
+		{"LOG_LEVEL", stringVar(&c.Log.Level)},
+		{"LOG_FORMAT", stringVar(&c.Log.Format)},
+	}
+}
+
+// applyEnv overrides settings with the environment variables that are set
+// and not empty
+func (c *Config) applyEnv(getenv func(string) string) error {
+	for _, v := range c.envVars() {
+		value := getenv(v.name)
+		if value == "" {
+			continue
+		}
+		if err := v.set(value); err != nil {
+			return fmt.Errorf("invalid %s %q: %w", v.name, value, err)
+		}
+	}
+	return nil
+}
+
+func stringVar(p *string) func(string) error {
+	return func(value string) error {
+		*p = value
+		return nil
+	}
+}
+
+func intVar(p *int) func(string) error {
+	return func(value string) error {
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("not an integer")
+		}
+		*p = n
+		return nil
+	}
+}
+
+func int64Var(p *int64) func(string) error {
+	return func(value string) error {
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return fmt.Errorf("not an integer")
+		}
+		*p = n
+		return nil
+	}
+}
+
+func boolVar(p *bool) func(string) error {
+	return func(value string) error {
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("not true or false")
+		}
+		*p = b
+		return nil
+	}
+}
+
+func floatVar(p *float64) func(string) error {
+	return func(value string) error {
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return fmt.Errorf("not a number")
+		}
+		*p = f
+		return nil
+	}
+}
+
+// secondsVar sets a duration from a whole number of seconds
+func secondsVar(p *time.Duration) func(string) error {
+	return func(value string) error {
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("not a whole number of seconds")
+		}
+		*p = time.Duration(n) * time.Second
+		return nil
+	}
+}
+
+// listVar sets a list from comma separated values, skipping empty entries
+func listVar(p *[]string) func(string) error {
+	return func(value string) error {
+		var values []string
+		for _, v := range strings.Split(value, ",") {
+			if v = strings.TrimSpace(v); v != "" {
+				values = append(values, v)
+			}
+		}
+		*p = values
+		return nil
+	}
+}
@@ -0,0 +1,177 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/svg-web-elements/internal/lint"
+	"github.com/svg-web-elements/internal/svg"
+)
+
+// MaxTemplateBytes is the largest template the admin API accepts
+const MaxTemplateBytes = 1 << 20
+
+// AdminHandler manages the templates in the template directory. Mount it
+// with http.StripPrefix so the request path is the template name, and
+// behind RequireToken.
+type AdminHandler struct {
+	processor *svg.Processor
+	registry  *svg.Registry
+}
+
+// NewAdminHandler creates a new admin handler
+func NewAdminHandler(processor *svg.Processor, registry *svg.Registry) *AdminHandler {
+	return &AdminHandler{
+		processor: processor,
+		registry:  registry,
+	}
+}
+
+// ServeHTTP handles GET, PUT and DELETE requests for templates
+func (h *AdminHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	name := strings.TrimPrefix(path.Clean("/"+r.URL.Path), "/")
+	if name == "" {
+		if r.Method != http.MethodGet {
+			w.Header().Set("Allow", http.MethodGet)
+			writeJSONError(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+		writeJSON(w, http.StatusOK, h.registry.List())
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		h.getTemplate(w, name)
+	case http.MethodPut:
+		h.putTemplate(w, r, name)
+	case http.MethodDelete:
+		h.deleteTemplate(w, r, name)
+	default:
+		w.Header().Set("Allow", "GET, PUT, DELETE")
+		writeJSONError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+// getTemplate returns the source of a template
+func (h *AdminHandler) getTemplate(w http.ResponseWriter, name string) {
+	source, err := h.processor.Source(name)
+	if err != nil {
+		writeJSONError(w, http.StatusNotFound, fmt.Sprintf("template %s not found", name))
+		return
+	}
+	setSVGHeaders(w)
+	w.Write(source)
+}
+
+// putTemplate sanitizes, validates and stores an uploaded template
+func (h *AdminHandler) putTemplate(w http.ResponseWriter, r *http.Request, name string) {
+	file, err := h.templateFile(name)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, MaxTemplateBytes))
+	if err != nil {
+		writeJSONError(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("templates are limited to %d bytes", MaxTemplateBytes))
+		return
+	}
+	data, err = svg.Sanitize(data)
+	if err != nil {
+		writeJSON(w, http.StatusUnprocessableEntity, map[string]interface{}{
+			"error":    "template failed validation",
+			"problems": []string{fmt.Sprintf("%s: %v", name, err)},
+		})
+		return
+	}
+
+	result := lint.SVG(name, data, lint.DefaultOptions())
+	var errs, warnings []string
+	for _, problem := range result.Problems {
+		if problem.Severity == lint.Error {
+			errs = append(errs, problem.String())
+		} else {
+			warnings = append(warnings, problem.String())
+		}
+	}
+	if len(errs) > 0 {
+		writeJSON(w, http.StatusUnprocessableEntity, map[string]interface{}{
+			"error":    "template failed validation",
+			"problems": errs,
+		})
+		return
+	}
+
+	_, statErr := os.Stat(file)
+	if err := svg.WriteFileAtomic(file, data); err != nil {
+		slog.ErrorContext(r.Context(), "Error storing template", "template", name, "error", err)
+		writeJSONError(w, http.StatusInternalServerError, "error storing template")
+		return
+	}
+	if err := h.registry.Reload(); err != nil {
+		slog.ErrorContext(r.Context(), "Error reloading templates", "error", err)
+	}
+	slog.InfoContext(r.Context(), "Stored template", "template", name, "bytes", len(data))
+
+	status := http.StatusOK
+	if errors.Is(statErr, fs.ErrNotExist) {
+		status = http.StatusCreated
+	}
+	info, _ := h.registry.Get(name)
+	writeJSON(w, status, map[string]interface{}{
+		"template": info,
+		"warnings": warnings,
+	})
+}
+
+// deleteTemplate removes a template from the template directory. Stock
+// templates it replaced are served again afterwards.
+func (h *AdminHandler) deleteTemplate(w http.ResponseWriter, r *http.Request, name string) {
+	file, err := h.templateFile(name)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := os.Remove(file); err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			writeJSONError(w, http.StatusNotFound, fmt.Sprintf("template %s not found in the template directory", name))
+			return
+		}
+		slog.ErrorContext(r.Context(), "Error deleting template", "template", name, "error", err)
+		writeJSONError(w, http.StatusInternalServerError, "error deleting template")
+		return
+	}
+	if err := h.registry.Reload(); err != nil {
+		slog.ErrorContext(r.Context(), "Error reloading templates", "error", err)
+	}
+	slog.InfoContext(r.Context(), "Deleted template", "template", name)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// templateFile returns the path a template is stored at in the template
+// directory
+func (h *AdminHandler) templateFile(name string) (string, error) {
+	if h.processor.BasePath == "" {
+		return "", errors.New("templates can't be changed without a template directory")
+	}
+	if !fs.ValidPath(name) || strings.HasPrefix(name, ".") || strings.Contains(name, "/.") ||
+		strings.HasPrefix(name, "_") || strings.Contains(name, "/_") {
+		return "", fmt.Errorf("invalid template name %s", name)
+	}
+	if strings.Contains(path.Base(name), "@") {
+		return "", fmt.Errorf("template name %s can't contain @, which pins versions", name)
+	}
+	if path.Ext(name) != ".svg" {
+		return "", fmt.Errorf("template name %s must end in .svg", name)
+	}
+	return filepath.Join(h.processor.BasePath, filepath.FromSlash(name)), nil
+}
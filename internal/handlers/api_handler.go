@@ -0,0 +1,165 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/svg-web-elements/internal/svg"
+)
+
+// MaxRequestBytes is the largest JSON body the API accepts
+const MaxRequestBytes = 1 << 20
+
+// APIHandler serves the JSON API for rendering templates
+type APIHandler struct {
+	// BatchWorkers limits how many jobs of a batch render at once,
+	// defaulting to the number of CPUs
+	BatchWorkers int
+	// Presets stores the presets created by PresetsHandler
+	Presets *svg.PresetStore
+
+	processor *svg.Processor
+}
+
+// NewAPIHandler creates a new API handler
+func NewAPIHandler(processor *svg.Processor) *APIHandler {
+	return &APIHandler{
+		processor: processor,
+	}
+}
+
+// apiError is the body of API error responses. Fields holds per-field
+// messages for invalid requests.
+type apiError struct {
+	Error  string          `json:"error"`
+	Fields svg.FieldErrors `json:"fields,omitempty"`
+}
+
+// templateRequest names a template and its parameters, given as for the
+// render API and as a raw query string
+type templateRequest struct {
+	Template string `json:"template"`
+	Query    string `json:"query,omitempty"`
+	svg.RenderRequest
+}
+
+// values validates the request and returns its parameters as a query.
+// The render API fields take precedence over the raw query.
+func (req templateRequest) values(limits svg.RenderLimits) (url.Values, svg.FieldErrors) {
+	errs := req.Validate(limits)
+	if req.Template == "" {
+		errs["template"] = "is required"
+	}
+	query, err := url.ParseQuery(strings.TrimPrefix(req.Query, "?"))
+	if err != nil {
+		errs["query"] = "is not a valid query string"
+	}
+	for key, values := range req.RenderRequest.Query() {
+		query[key] = values
+	}
+	return query, errs
+}
+
+// RenderHandler renders the template named by the request path from a JSON
+// RenderRequest. Mount it with http.StripPrefix so the request path is the
+// template name.
+func (h *APIHandler) RenderHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		writeJSONError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	svgName, version, err := svg.SplitVersion(strings.TrimPrefix(path.Clean("/"+r.URL.Path), "/"))
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var req svg.RenderRequest
+	if err := decodeJSON(w, r, &req); err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if req.Version == 0 {
+		req.Version = version
+	}
+
+	data, contentType, status, apiErr := h.render(r, svgName, req)
+	if apiErr != nil {
+		writeJSON(w, status, apiErr)
+		return
+	}
+
+	if contentType == "image/svg+xml" {
+		setSVGHeaders(w)
+	} else {
+		w.Header().Set("Content-Type", contentType)
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+	}
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Content-Length", fmt.Sprintf("%d", len(data)))
+	if _, err := w.Write(data); err != nil {
+		slog.WarnContext(r.Context(), "Error writing render response", "template", svgName, "error", err)
+	}
+}
+
+// render validates and renders a request, returning the output and its
+// content type, or the status and body of the error response
+func (h *APIHandler) render(r *http.Request, svgName string, req svg.RenderRequest) ([]byte, string, int, *apiError) {
+	if errs := req.Validate(h.processor.Limits); len(errs) > 0 {
+		return nil, "", http.StatusUnprocessableEntity, &apiError{Error: "invalid request", Fields: errs}
+	}
+
+	params, err := svg.ParseQuery(req.Query())
+	if err != nil {
+		return nil, "", http.StatusBadRequest, &apiError{Error: err.Error()}
+	}
+
+	data, err := h.processor.ProcessSVGContext(r.Context(), svgName, params)
+	if errors.Is(err, svg.ErrNotFound) {
+		return nil, "", http.StatusNotFound, &apiError{Error: err.Error()}
+	}
+	if errors.Is(err, svg.ErrInvalidImage) {
+		return nil, "", http.StatusUnprocessableEntity, &apiError{Error: err.Error()}
+	}
+	if errors.Is(err, svg.ErrLimitExceeded) {
+		return nil, "", http.StatusBadRequest, &apiError{Error: err.Error()}
+	}
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Error processing SVG", "template", svgName, "error", err)
+		return nil, "", http.StatusInternalServerError, &apiError{Error: fmt.Sprintf("error processing SVG: %v", err)}
+	}
+
+	// Report IDs the template doesn't have instead of silently ignoring them
+	if errs := req.UnknownIDs(svg.ElementIDs(data)); len(errs) > 0 {
+		return nil, "", http.StatusUnprocessableEntity, &apiError{Error: "invalid request", Fields: errs}
+	}
+
+	if strings.EqualFold(req.Format, "png") {
+		data, err = svg.RasterizePNG(r.Context(), data)
+		if err != nil {
+			slog.ErrorContext(r.Context(), "Error rasterizing SVG", "template", svgName, "error", err)
+			return nil, "", http.StatusInternalServerError, &apiError{Error: "error converting SVG to PNG"}
+		}
+		return data, "image/png", http.StatusOK, nil
+	}
+	return data, "image/svg+xml", http.StatusOK, nil
+}
+
+// decodeJSON decodes a size-limited JSON request body, rejecting unknown
+// fields so typos don't go unnoticed
+func decodeJSON(w http.ResponseWriter, r *http.Request, v interface{}) error {
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, MaxRequestBytes))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(v); err != nil {
+		return fmt.Errorf("invalid JSON body: %v", err)
+	}
+	return nil
+}
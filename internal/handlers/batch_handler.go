@@ -0,0 +1,294 @@
+package handlers
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"net/http"
+	"path"
+	"runtime"
+	"runtime/debug"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/svg-web-elements/internal/svg"
+)
+
+// MaxBatchJobs is the largest number of jobs in a batch
+const MaxBatchJobs = 500
+
+// batchRequest is the body of a batch render request
+type batchRequest struct {
+	Jobs []batchJob `json:"jobs"`
+}
+
+// batchJob is a single render in a batch: a template and its parameters,
+// and the name of the output in the response
+type batchJob struct {
+	Template string `json:"template"`
+	Output   string `json:"output,omitempty"`
+	svg.RenderRequest
+}
+
+// batchResult reports the outcome of a job. Data is base64 encoded in JSON.
+type batchResult struct {
+	Index       int             `json:"index"`
+	Template    string          `json:"template"`
+	Output      string          `json:"output"`
+	Status      int             `json:"status"`
+	ContentType string          `json:"content_type,omitempty"`
+	Size        int             `json:"size,omitempty"`
+	Error       string          `json:"error,omitempty"`
+	Fields      svg.FieldErrors `json:"fields,omitempty"`
+	Data        []byte          `json:"data,omitempty"`
+}
+
+// BatchHandler renders a list of jobs concurrently. The results are
+// streamed as a zip archive with a report.json of every job, or as one JSON
+// line per job when the client accepts application/x-ndjson.
+func (h *APIHandler) BatchHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		writeJSONError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	var req batchRequest
+	if err := decodeJSON(w, r, &req); err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if len(req.Jobs) == 0 {
+		writeJSONError(w, http.StatusBadRequest, "the batch has no jobs")
+		return
+	}
+	if len(req.Jobs) > MaxBatchJobs {
+		writeJSONError(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("batches are limited to %d jobs", MaxBatchJobs))
+		return
+	}
+	if errs := assignOutputs(req.Jobs); len(errs) > 0 {
+		writeJSON(w, http.StatusUnprocessableEntity, apiError{Error: "invalid request", Fields: errs})
+		return
+	}
+
+	var write func(batchResult) error
+	var finish func([]batchResult) error
+	if strings.Contains(r.Header.Get("Accept"), "application/x-ndjson") {
+		write, finish = h.ndjsonWriter(w)
+	} else {
+		write, finish = h.zipWriter(w)
+	}
+
+	results := make([]batchResult, 0, len(req.Jobs))
+	var writeErr error
+	for result := range h.runBatch(r, req.Jobs) {
+		if writeErr == nil {
+			writeErr = write(result)
+		}
+		result.Data = nil
+		results = append(results, result)
+	}
+	if writeErr == nil {
+		writeErr = finish(results)
+	}
+	if writeErr != nil {
+		slog.WarnContext(r.Context(), "Error writing batch response", "error", writeErr)
+	}
+
+	failed := 0
+	for _, result := range results {
+		if result.Status != http.StatusOK {
+			failed++
+		}
+	}
+	slog.InfoContext(r.Context(), "Rendered batch", "jobs", len(results), "failed", failed)
+}
+
+// runBatch renders the jobs with a bounded number of workers and returns
+// the results in the order they finish. Jobs are no longer started once the
+// client goes away.
+func (h *APIHandler) runBatch(r *http.Request, jobs []batchJob) <-chan batchResult {
+	workers := h.BatchWorkers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+
+	indexes := make(chan int)
+	results := make(chan batchResult)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for index := range indexes {
+				results <- h.renderJob(r, index, jobs[index])
+			}
+		}()
+	}
+
+	go func() {
+		defer close(indexes)
+		for index := range jobs {
+			select {
+			case indexes <- index:
+			case <-r.Context().Done():
+				return
+			}
+		}
+	}()
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+	return results
+}
+
+// renderJob renders a single job of a batch. A panic while rendering fails
+// the job rather than the server.
+func (h *APIHandler) renderJob(r *http.Request, index int, job batchJob) (result batchResult) {
+	result = batchResult{
+		Index:    index,
+		Template: job.Template,
+		Output:   job.Output,
+	}
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			slog.ErrorContext(r.Context(), "Panic rendering batch job", "index", index,
+				"template", job.Template, "panic", recovered, "stack", string(debug.Stack()))
+			result.Status, result.Error = http.StatusInternalServerError, "internal error"
+			result.ContentType, result.Size, result.Data = "", 0, nil
+		}
+	}()
+
+	svgName, version, err := svg.SplitVersion(job.Template)
+	if err != nil {
+		result.Status, result.Error = http.StatusBadRequest, err.Error()
+		return result
+	}
+	if job.Version == 0 {
+		job.Version = version
+	}
+
+	data, contentType, status, apiErr := h.render(r, svgName, job.RenderRequest)
+	result.Status = status
+	if apiErr != nil {
+		result.Error, result.Fields = apiErr.Error, apiErr.Fields
+		return result
+	}
+	result.ContentType, result.Size, result.Data = contentType, len(data), data
+	return result
+}
+
+// assignOutputs checks the jobs' templates and output names and names the
+// outputs of jobs that have none after their index and template
+func assignOutputs(jobs []batchJob) svg.FieldErrors {
+	errs := make(svg.FieldErrors)
+	seen := make(map[string]int)
+	for i := range jobs {
+		job := &jobs[i]
+		field := fmt.Sprintf("jobs[%d]", i)
+		if job.Template == "" {
+			errs[field+".template"] = "is required"
+			continue
+		}
+
+		ext := ".svg"
+		if strings.EqualFold(job.Format, "png") {
+			ext = ".png"
+		}
+		if job.Output == "" {
+			base := path.Base(job.Template)
+			job.Output = fmt.Sprintf("%d-%s%s", i+1, strings.TrimSuffix(base, path.Ext(base)), ext)
+		}
+		if !fs.ValidPath(job.Output) || job.Output == "." || job.Output == "report.json" {
+			errs[field+".output"] = "must be a relative path other than report.json"
+			continue
+		}
+		if first, ok := seen[job.Output]; ok {
+			errs[field+".output"] = fmt.Sprintf("is already used by jobs[%d]", first)
+			continue
+		}
+		seen[job.Output] = i
+	}
+	return errs
+}
+
+// zipWriter streams results as entries of a zip archive, followed by a
+// report of every job
+func (h *APIHandler) zipWriter(w http.ResponseWriter) (func(batchResult) error, func([]batchResult) error) {
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", `attachment; filename="batch.zip"`)
+	archive := zip.NewWriter(w)
+
+	write := func(result batchResult) error {
+		if result.Status != http.StatusOK {
+			return nil
+		}
+		return writeZipEntry(archive, result.Output, result.ContentType != "image/png", result.Data)
+	}
+	finish := func(results []batchResult) error {
+		report, err := json.MarshalIndent(map[string]interface{}{"jobs": sortResults(results)}, "", "  ")
+		if err != nil {
+			return err
+		}
+		if err := writeZipEntry(archive, "report.json", true, report); err != nil {
+			return err
+		}
+		return archive.Close()
+	}
+	return write, finish
+}
+
+// writeZipEntry adds a file to a zip archive, compressing text formats
+func writeZipEntry(archive *zip.Writer, name string, compress bool, data []byte) error {
+	header := &zip.FileHeader{
+		Name:     name,
+		Method:   zip.Store,
+		Modified: time.Now(),
+	}
+	if compress {
+		header.Method = zip.Deflate
+	}
+	entry, err := archive.CreateHeader(header)
+	if err != nil {
+		return err
+	}
+	_, err = entry.Write(data)
+	return err
+}
+
+// ndjsonWriter streams every result as a line of JSON with its data base64
+// encoded, flushing after each line
+func (h *APIHandler) ndjsonWriter(w http.ResponseWriter) (func(batchResult) error, func([]batchResult) error) {
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	encoder := json.NewEncoder(w)
+	flusher, _ := w.(http.Flusher)
+
+	write := func(result batchResult) error {
+		if err := encoder.Encode(result); err != nil {
+			return err
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+		return nil
+	}
+	finish := func([]batchResult) error {
+		return nil
+	}
+	return write, finish
+}
+
+// sortResults orders results by job index
+func sortResults(results []batchResult) []batchResult {
+	sorted := make([]batchResult, len(results))
+	copy(sorted, results)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Index < sorted[j].Index
+	})
+	return sorted
+}
@@ -0,0 +1,131 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/svg-web-elements/internal/svg"
+)
+
+// HealthHandler answers liveness and readiness probes
+type HealthHandler struct {
+	registry    *svg.Registry
+	templateDir string
+	cacheDir    string
+}
+
+// healthCheck is the outcome of one readiness check
+type healthCheck struct {
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// NewHealthHandler creates a health handler checking a template registry,
+// the template directory it reads and the directory revisions and presets
+// are written to
+func NewHealthHandler(registry *svg.Registry, templateDir, cacheDir string) *HealthHandler {
+	return &HealthHandler{
+		registry:    registry,
+		templateDir: templateDir,
+		cacheDir:    cacheDir,
+	}
+}
+
+// Live reports that the server is up
+func (h *HealthHandler) Live(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// Ready reports whether the server can serve templates, with the outcome of
+// each check. It answers 503 when any check fails.
+func (h *HealthHandler) Ready(w http.ResponseWriter, r *http.Request) {
+	checks := map[string]healthCheck{
+		"template_dir": checkResult(h.checkTemplateDir()),
+		"templates":    checkResult(h.checkTemplates()),
+		"cache_dir":    checkResult(h.checkCacheDir()),
+		"reload":       checkResult(h.checkReload()),
+	}
+
+	status, code := "ok", http.StatusOK
+	for _, check := range checks {
+		if check.Status != "ok" {
+			status, code = "fail", http.StatusServiceUnavailable
+		}
+	}
+	writeJSON(w, code, map[string]interface{}{
+		"status": status,
+		"checks": checks,
+	})
+}
+
+// checkTemplateDir checks that the template directory can be listed. A
+// missing directory is fine: the built-in templates are served without it.
+func (h *HealthHandler) checkTemplateDir() error {
+	_, err := os.ReadDir(h.templateDir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+// checkTemplates checks that there are templates and that all of them
+// render
+func (h *HealthHandler) checkTemplates() error {
+	status := h.registry.Status()
+	if status.Templates == 0 {
+		return fmt.Errorf("no templates")
+	}
+	if len(status.Invalid) == 0 {
+		return nil
+	}
+	names := make([]string, 0, len(status.Invalid))
+	for name := range status.Invalid {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	problems := make([]string, len(names))
+	for i, name := range names {
+		problems[i] = fmt.Sprintf("%s: %v", name, status.Invalid[name])
+	}
+	return fmt.Errorf("%d invalid: %s", len(names), strings.Join(problems, "; "))
+}
+
+// checkCacheDir checks that files can be created in the cache directory
+func (h *HealthHandler) checkCacheDir() error {
+	if err := os.MkdirAll(h.cacheDir, 0755); err != nil {
+		return err
+	}
+	file, err := os.CreateTemp(h.cacheDir, ".readyz-*")
+	if err != nil {
+		return err
+	}
+	file.Close()
+	return os.Remove(file.Name())
+}
+
+// checkReload checks that templates have been loaded and the last reload
+// succeeded
+func (h *HealthHandler) checkReload() error {
+	status := h.registry.Status()
+	if status.LastReload.IsZero() {
+		return fmt.Errorf("templates have not been loaded")
+	}
+	if status.LastError != nil {
+		return fmt.Errorf("last reload at %s failed: %v", status.LastReload.UTC().Format(time.RFC3339), status.LastError)
+	}
+	return nil
+}
+
+// checkResult turns the error of a check into its outcome
+func checkResult(err error) healthCheck {
+	if err != nil {
+		return healthCheck{Status: "fail", Error: err.Error()}
+	}
+	return healthCheck{Status: "ok"}
+}
@@ -0,0 +1,236 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/svg-web-elements/internal/metrics"
+	"github.com/svg-web-elements/internal/svg"
+)
+
+// unknownTemplate labels requests for templates that don't exist, so
+// arbitrary names don't create new series
+const unknownTemplate = "unknown"
+
+// Metrics collects request and render metrics and serves them in the
+// Prometheus text format. It is the processor's Observer.
+type Metrics struct {
+	registry  *metrics.Registry
+	templates *svg.Registry
+
+	requests           *metrics.CounterVec
+	requestDuration    *metrics.HistogramVec
+	validationFailures *metrics.CounterVec
+	renders            *metrics.CounterVec
+	renderDuration     *metrics.HistogramVec
+	cacheLookups       *metrics.CounterVec
+}
+
+// NewMetrics creates the service's metrics for a template registry and an
+// optional render cache
+func NewMetrics(templates *svg.Registry, cache *svg.RenderCache) *Metrics {
+	registry := metrics.NewRegistry()
+	m := &Metrics{
+		registry:  registry,
+		templates: templates,
+		requests: registry.NewCounterVec("svgwe_http_requests_total",
+			"HTTP requests by handler, template, status and response format.",
+			"handler", "template", "status", "format"),
+		requestDuration: registry.NewHistogramVec("svgwe_http_request_duration_seconds",
+			"Time to answer HTTP requests by handler.",
+			metrics.DefBuckets, "handler"),
+		validationFailures: registry.NewCounterVec("svgwe_validation_failures_total",
+			"Requests rejected as invalid by handler.",
+			"handler"),
+		renders: registry.NewCounterVec("svgwe_renders_total",
+			"Template renders by template and result, not counting cache hits.",
+			"template", "result"),
+		renderDuration: registry.NewHistogramVec("svgwe_render_duration_seconds",
+			"Time to render templates by template, not counting cache hits.",
+			metrics.DefBuckets, "template"),
+		cacheLookups: registry.NewCounterVec("svgwe_render_cache_lookups_total",
+			"Render cache lookups by result.",
+			"result"),
+	}
+
+	registry.NewGaugeFunc("svgwe_render_cache_hit_ratio", "Share of render cache lookups that were hits.", func() float64 {
+		hits, misses := m.cacheLookups.Value("hit"), m.cacheLookups.Value("miss")
+		if hits+misses == 0 {
+			return 0
+		}
+		return hits / (hits + misses)
+	})
+	registry.NewGaugeFunc("svgwe_render_cache_entries", "Renders in the render cache.", func() float64 {
+		if cache == nil {
+			return 0
+		}
+		entries, _ := cache.Stats()
+		return float64(entries)
+	})
+	registry.NewGaugeFunc("svgwe_render_cache_bytes", "Size of the renders in the render cache.", func() float64 {
+		if cache == nil {
+			return 0
+		}
+		_, bytes := cache.Stats()
+		return float64(bytes)
+	})
+	registry.NewGaugeFunc("svgwe_templates", "Templates in the registry.", func() float64 {
+		return float64(templates.Status().Templates)
+	})
+	registry.NewCounterFunc("svgwe_template_reloads_total", "Template registry reloads.", func() float64 {
+		return float64(templates.Status().Reloads)
+	})
+	registry.NewCounterFunc("svgwe_template_reload_failures_total", "Template registry reloads that failed.", func() float64 {
+		return float64(templates.Status().Failures)
+	})
+	registry.NewGaugeFunc("svgwe_template_last_reload_timestamp_seconds", "Unix time of the last template registry reload.", func() float64 {
+		return float64(templates.Status().LastReload.UnixNano()) / 1e9
+	})
+	return m
+}
+
+// Handler serves the metrics for scraping
+func (m *Metrics) Handler() http.Handler {
+	return m.registry.Handler()
+}
+
+// RenderDone records a render, implementing svg.Observer
+func (m *Metrics) RenderDone(svgName string, duration time.Duration, err error) {
+	template := m.templateLabel(svgName)
+	result := "ok"
+	switch {
+	case errors.Is(err, svg.ErrNotFound):
+		result = "not_found"
+	case errors.Is(err, svg.ErrLimitExceeded):
+		result = "limit_exceeded"
+	case errors.Is(err, svg.ErrInvalidImage):
+		result = "invalid_image"
+	case err != nil:
+		result = "error"
+	}
+	m.renders.Inc(template, result)
+	m.renderDuration.Observe(duration.Seconds(), template)
+}
+
+// CacheLookup records a render cache lookup, implementing svg.Observer
+func (m *Metrics) CacheLookup(hit bool) {
+	if hit {
+		m.cacheLookups.Inc("hit")
+	} else {
+		m.cacheLookups.Inc("miss")
+	}
+}
+
+// Instrument counts and times the requests answered by next. The template
+// label is taken from the request with template, or left empty.
+func (m *Metrics) Instrument(handler string, template func(*http.Request) string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(recorder, r)
+
+		label := ""
+		if template != nil {
+			label = template(r)
+		}
+		m.requests.Inc(handler, label, strconv.Itoa(recorder.status), responseFormat(recorder.Header().Get("Content-Type")))
+		m.requestDuration.Observe(time.Since(start).Seconds(), handler)
+		if recorder.status == http.StatusBadRequest || recorder.status == http.StatusUnprocessableEntity {
+			m.validationFailures.Inc(handler)
+		}
+	})
+}
+
+// PathTemplate returns a template label function for handlers serving
+// templates by name under prefix, such as /ui/
+func (m *Metrics) PathTemplate(prefix string) func(*http.Request) string {
+	return func(r *http.Request) string {
+		name := strings.TrimPrefix(path.Clean(r.URL.Path), prefix)
+		svgName, _, err := svg.SplitVersion(name)
+		if err != nil {
+			return unknownTemplate
+		}
+		return m.templateLabel(svgName)
+	}
+}
+
+// PresetTemplate returns a template label function for the preset
+// handler mounted under prefix, labelling requests with the preset's
+// template
+func (m *Metrics) PresetTemplate(prefix string, presets *svg.PresetStore) func(*http.Request) string {
+	return func(r *http.Request) string {
+		preset, ok := presets.Get(svg.PresetID(strings.TrimPrefix(path.Clean(r.URL.Path), prefix)))
+		if !ok {
+			return unknownTemplate
+		}
+		svgName, _, err := svg.SplitVersion(preset.Template)
+		if err != nil {
+			return unknownTemplate
+		}
+		return m.templateLabel(svgName)
+	}
+}
+
+// templateLabel returns the label for a template name
+func (m *Metrics) templateLabel(svgName string) string {
+	if _, ok := m.templates.Get(svgName); ok {
+		return svgName
+	}
+	return unknownTemplate
+}
+
+// responseFormat names the format of a response by its content type
+func responseFormat(contentType string) string {
+	mediaType, _, _ := strings.Cut(contentType, ";")
+	switch strings.TrimSpace(mediaType) {
+	case "image/svg+xml":
+		return "svg"
+	case "image/png":
+		return "png"
+	case "application/zip":
+		return "zip"
+	case "application/x-ndjson":
+		return "ndjson"
+	case "application/json":
+		return "json"
+	case "text/plain":
+		return "text"
+	case "":
+		return "none"
+	}
+	return "other"
+}
+
+// statusRecorder remembers the status code and size of a response
+type statusRecorder struct {
+	http.ResponseWriter
+	status      int
+	bytes       int
+	wroteHeader bool
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	if !r.wroteHeader {
+		r.status = status
+		r.wroteHeader = true
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *statusRecorder) Write(p []byte) (int, error) {
+	r.wroteHeader = true
+	n, err := r.ResponseWriter.Write(p)
+	r.bytes += n
+	return n, err
+}
+
+// Flush passes flushes through, so streamed responses keep streaming
+func (r *statusRecorder) Flush() {
+	if flusher, ok := r.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
@@ -0,0 +1,179 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/svg-web-elements/internal/svg"
+)
+
+// errPresetOverride rejects query parameters on preset URLs when only
+// signed URLs may be rendered
+var errPresetOverride = errors.New("preset parameters can't be overridden")
+
+// presetResponse describes a saved preset and the URL it is served at
+type presetResponse struct {
+	ID     string     `json:"id"`
+	URL    string     `json:"url"`
+	Preset svg.Preset `json:"preset"`
+}
+
+// presetURL returns the path a preset is served at
+func presetURL(id string) string {
+	return "/p/" + id + ".svg"
+}
+
+// PresetsHandler saves the template and parameters of a JSON request as a
+// preset. The preset is rendered once so broken presets are rejected.
+func (h *APIHandler) PresetsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		writeJSONError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	var req templateRequest
+	if err := decodeJSON(w, r, &req); err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	query, errs := req.values(h.processor.Limits)
+	if len(errs) > 0 {
+		writeJSON(w, http.StatusUnprocessableEntity, apiError{Error: "invalid request", Fields: errs})
+		return
+	}
+
+	svgName, version, err := svg.SplitVersion(req.Template)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	params, err := svg.ParseQuery(query)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if version > 0 {
+		params.Version = version
+	}
+	data, err := h.processor.ProcessSVGContext(r.Context(), svgName, params)
+	if errors.Is(err, svg.ErrNotFound) {
+		writeJSONError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	if err != nil {
+		writeJSONError(w, http.StatusUnprocessableEntity, fmt.Sprintf("the preset doesn't render: %v", err))
+		return
+	}
+	if errs := req.UnknownIDs(svg.ElementIDs(data)); len(errs) > 0 {
+		writeJSON(w, http.StatusUnprocessableEntity, apiError{Error: "invalid request", Fields: errs})
+		return
+	}
+
+	preset, err := h.Presets.Save(req.Template, query)
+	if errors.Is(err, svg.ErrLimitExceeded) {
+		writeJSONError(w, http.StatusRequestEntityTooLarge, err.Error())
+		return
+	}
+	if errors.Is(err, svg.ErrPresetStoreFull) {
+		slog.WarnContext(r.Context(), "Rejected preset", "template", req.Template, "error", err)
+		writeJSONError(w, http.StatusInsufficientStorage, err.Error())
+		return
+	}
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Error saving preset", "template", req.Template, "error", err)
+		writeJSONError(w, http.StatusInternalServerError, "error saving preset")
+		return
+	}
+	slog.InfoContext(r.Context(), "Saved preset", "preset", preset.ID, "template", preset.Template)
+	writeJSON(w, http.StatusCreated, presetResponse{ID: preset.ID, URL: presetURL(preset.ID), Preset: preset})
+}
+
+// PresetHandler serves presets by ID, e.g. abc123.svg. Query parameters
+// override those of the preset, unless only signed URLs may be rendered.
+// Mount it with http.StripPrefix so the request path is the preset.
+func (h *SVGHandler) PresetHandler(w http.ResponseWriter, r *http.Request) {
+	name := strings.TrimPrefix(path.Clean("/"+r.URL.Path), "/")
+	preset, ok := h.Presets.Get(svg.PresetID(name))
+	if !ok {
+		http.Error(w, fmt.Sprintf("Preset %s not found", name), http.StatusNotFound)
+		return
+	}
+
+	overrides := r.URL.Query()
+	if len(overrides) > 0 && h.Signer != nil {
+		slog.InfoContext(r.Context(), "Rejected preset overrides", "preset", preset.ID)
+		writeForbiddenSVG(w, errPresetOverride)
+		return
+	}
+
+	slog.DebugContext(r.Context(), "Rendering preset", "preset", preset.ID, "template", preset.Template)
+	query := preset.Values()
+	for key, values := range overrides {
+		query[key] = values
+	}
+	h.serveSVG(w, r, preset.Template, query)
+}
+
+// PresetAdminHandler lists and deletes presets. Mount it with
+// http.StripPrefix so the request path is the preset ID, and behind
+// RequireToken.
+type PresetAdminHandler struct {
+	presets *svg.PresetStore
+}
+
+// NewPresetAdminHandler creates a new preset admin handler
+func NewPresetAdminHandler(presets *svg.PresetStore) *PresetAdminHandler {
+	return &PresetAdminHandler{
+		presets: presets,
+	}
+}
+
+// ServeHTTP lists presets, or returns or deletes a single preset
+func (h *PresetAdminHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	id := svg.PresetID(strings.TrimPrefix(path.Clean("/"+r.URL.Path), "/"))
+	if id == "" {
+		if r.Method != http.MethodGet {
+			w.Header().Set("Allow", http.MethodGet)
+			writeJSONError(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+		presets := h.presets.List()
+		list := make([]presetResponse, len(presets))
+		for i, preset := range presets {
+			list[i] = presetResponse{ID: preset.ID, URL: presetURL(preset.ID), Preset: preset}
+		}
+		writeJSON(w, http.StatusOK, list)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		preset, ok := h.presets.Get(id)
+		if !ok {
+			writeJSONError(w, http.StatusNotFound, fmt.Sprintf("preset %s not found", id))
+			return
+		}
+		writeJSON(w, http.StatusOK, presetResponse{ID: preset.ID, URL: presetURL(preset.ID), Preset: preset})
+	case http.MethodDelete:
+		deleted, err := h.presets.Delete(id)
+		if err != nil {
+			slog.ErrorContext(r.Context(), "Error deleting preset", "preset", id, "error", err)
+			writeJSONError(w, http.StatusInternalServerError, "error deleting preset")
+			return
+		}
+		if !deleted {
+			writeJSONError(w, http.StatusNotFound, fmt.Sprintf("preset %s not found", id))
+			return
+		}
+		slog.InfoContext(r.Context(), "Deleted preset", "preset", id)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.Header().Set("Allow", "GET, DELETE")
+		writeJSONError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
@@ -0,0 +1,171 @@
+package handlers
+
+import (
+	"fmt"
+	"log/slog"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// rateLimitSweepInterval is how often buckets of idle clients are dropped
+const rateLimitSweepInterval = time.Minute
+
+// RateLimiter limits requests per client IP with token buckets. Each client
+// may send Burst requests at once and Rate requests per second after that.
+type RateLimiter struct {
+	// Rate is the number of requests per second a client may send
+	Rate float64
+	// Burst is the number of requests a client may send at once
+	Burst int
+	// TrustedProxies are the networks whose X-Forwarded-For headers are
+	// believed, such as the reverse proxy in front of the service
+	TrustedProxies []*net.IPNet
+
+	mu        sync.Mutex
+	buckets   map[string]*tokenBucket
+	lastSweep time.Time
+}
+
+// tokenBucket holds the tokens a client has left
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// NewRateLimiter creates a rate limiter
+func NewRateLimiter(rate float64, burst int, trustedProxies []*net.IPNet) *RateLimiter {
+	if burst < 1 {
+		burst = 1
+	}
+	return &RateLimiter{
+		Rate:           rate,
+		Burst:          burst,
+		TrustedProxies: trustedProxies,
+		buckets:        make(map[string]*tokenBucket),
+	}
+}
+
+// Allow takes a token from a client's bucket. When the bucket is empty it
+// returns how long until the next token is available.
+func (l *RateLimiter) Allow(client string, now time.Time) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if now.Sub(l.lastSweep) > rateLimitSweepInterval {
+		l.sweep(now)
+	}
+
+	bucket, ok := l.buckets[client]
+	if !ok {
+		bucket = &tokenBucket{tokens: float64(l.Burst), last: now}
+		l.buckets[client] = bucket
+	}
+	bucket.tokens = math.Min(float64(l.Burst), bucket.tokens+now.Sub(bucket.last).Seconds()*l.Rate)
+	bucket.last = now
+
+	if bucket.tokens >= 1 {
+		bucket.tokens--
+		return true, 0
+	}
+	wait := time.Duration((1 - bucket.tokens) / l.Rate * float64(time.Second))
+	return false, wait
+}
+
+// sweep drops the buckets of clients that have been idle long enough for
+// their bucket to be full again
+func (l *RateLimiter) sweep(now time.Time) {
+	full := time.Duration(float64(l.Burst) / l.Rate * float64(time.Second))
+	for client, bucket := range l.buckets {
+		if now.Sub(bucket.last) > full {
+			delete(l.buckets, client)
+		}
+	}
+	l.lastSweep = now
+}
+
+// Middleware answers requests from clients over their limit with 429
+func (l *RateLimiter) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		client := ClientIP(r, l.TrustedProxies)
+		if ok, wait := l.Allow(client, time.Now()); !ok {
+			slog.InfoContext(r.Context(), "Rate limited", "client", client)
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+			http.Error(w, "Too many requests", http.StatusTooManyRequests)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// ClientIP returns the IP of the client that sent a request. Requests from
+// trusted proxies are attributed to the last address in X-Forwarded-For
+// that isn't a trusted proxy itself.
+func ClientIP(r *http.Request, trustedProxies []*net.IPNet) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	if !isTrusted(host, trustedProxies) {
+		return host
+	}
+
+	var hops []string
+	for _, header := range r.Header.Values("X-Forwarded-For") {
+		for _, hop := range strings.Split(header, ",") {
+			hops = append(hops, strings.TrimSpace(hop))
+		}
+	}
+	for i := len(hops) - 1; i >= 0; i-- {
+		if net.ParseIP(hops[i]) == nil {
+			break
+		}
+		host = hops[i]
+		if !isTrusted(host, trustedProxies) {
+			break
+		}
+	}
+	return host
+}
+
+// isTrusted reports whether an IP belongs to a trusted network
+func isTrusted(host string, trustedProxies []*net.IPNet) bool {
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return false
+	}
+	for _, network := range trustedProxies {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseNetworks parses a list of CIDR networks and single IPs
+func ParseNetworks(list []string) ([]*net.IPNet, error) {
+	var networks []*net.IPNet
+	for _, entry := range list {
+		if !strings.Contains(entry, "/") {
+			ip := net.ParseIP(entry)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid IP %q", entry)
+			}
+			bits := 8 * len(ip.To4())
+			if bits == 0 {
+				bits = 8 * net.IPv6len
+			}
+			entry = fmt.Sprintf("%s/%d", entry, bits)
+		}
+		_, network, err := net.ParseCIDR(entry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid network %q: %w", entry, err)
+		}
+		networks = append(networks, network)
+	}
+	return networks, nil
+}
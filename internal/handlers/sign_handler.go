@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/svg-web-elements/internal/svg"
+)
+
+// SignHandler mints signed /ui/ URLs for trusted users. Mount it behind
+// RequireToken.
+type SignHandler struct {
+	// Limits are checked when signing, so signed URLs render
+	Limits svg.RenderLimits
+
+	signer *svg.Signer
+}
+
+// NewSignHandler creates a handler minting URLs signed by signer
+func NewSignHandler(signer *svg.Signer) *SignHandler {
+	return &SignHandler{
+		Limits: svg.DefaultLimits(),
+		signer: signer,
+	}
+}
+
+// signRequest describes the URL to sign and how long it is valid
+type signRequest struct {
+	templateRequest
+	ExpiresIn int `json:"expires_in,omitempty"`
+}
+
+// ServeHTTP signs the URL described by a JSON signRequest
+func (h *SignHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		writeJSONError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	var req signRequest
+	if err := decodeJSON(w, r, &req); err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	query, errs := req.values(h.Limits)
+	if req.ExpiresIn < 0 {
+		errs["expires_in"] = "must be a positive number of seconds"
+	}
+	if len(errs) > 0 {
+		writeJSON(w, http.StatusUnprocessableEntity, apiError{Error: "invalid request", Fields: errs})
+		return
+	}
+
+	var expires time.Time
+	if req.ExpiresIn > 0 {
+		expires = time.Now().Add(time.Duration(req.ExpiresIn) * time.Second)
+	}
+	signed := h.signer.Sign(req.Template, query, expires)
+
+	response := map[string]interface{}{
+		"url": "/ui/" + req.Template + "?" + signed.Encode(),
+	}
+	if !expires.IsZero() {
+		response["expires"] = expires.UTC().Format(time.RFC3339)
+	}
+	writeJSON(w, http.StatusOK, response)
+}
@@ -1,142 +1,221 @@
 package handlers
 
 import (
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
 	"net/http"
 	"net/url"
-	"path/filepath"
+	"path"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/svg-web-elements/internal/svg"
 )
 
 // SVGHandler handles requests for SVG files
 type SVGHandler struct {
+	// Signer requires requests to carry a valid signature when set
+	Signer *svg.Signer
+	// Presets holds the parameter sets served by PresetHandler
+	Presets *svg.PresetStore
+
 	processor *svg.Processor
+	registry  *svg.Registry
 }
 
-// NewSVGHandler creates a new SVG handler
-func NewSVGHandler(svgBasePath string) *SVGHandler {
+// NewSVGHandler creates a new SVG handler and indexes the processor's
+// templates
+func NewSVGHandler(processor *svg.Processor) *SVGHandler {
+	registry := svg.NewRegistry(processor)
+	if err := registry.Reload(); err != nil {
+		slog.Error("Error loading templates", "error", err)
+	}
 	return &SVGHandler{
-		processor: svg.NewProcessor(svgBasePath),
+		processor: processor,
+		registry:  registry,
 	}
 }
 
+// Registry returns the index of templates listed by the handler
+func (h *SVGHandler) Registry() *svg.Registry {
+	return h.registry
+}
+
 // ServeHTTP handles HTTP requests for SVGs
 func (h *SVGHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	// Extract the SVG name from the URL path
-	path := r.URL.Path
-	svgName := filepath.Base(path)
-	
-	log.Printf("SVG request: %s, User-Agent: %s", svgName, r.UserAgent())
-	log.Printf("Query parameters: %v", r.URL.RawQuery)
+	// Extract the SVG name from the URL path, which may include subdirectories
+	// such as auth/basic.svg
+	svgName := strings.TrimPrefix(path.Clean("/"+r.URL.Path), "/")
+
+	// Only render signed URLs, so arbitrary text can't be put in templates
+	if h.Signer != nil {
+		if err := h.Signer.Verify(svgName, r.URL.Query(), time.Now()); err != nil {
+			slog.InfoContext(r.Context(), "Rejected unsigned request", "template", svgName, "error", err)
+			writeForbiddenSVG(w, err)
+			return
+		}
+	}
+
+	h.serveSVG(w, r, svgName, r.URL.Query())
+}
+
+// serveSVG renders a template with the given query parameters
+func (h *SVGHandler) serveSVG(w http.ResponseWriter, r *http.Request, svgName string, query url.Values) {
+	// A version can be pinned in the name, e.g. basic-auth@3.svg
+	svgName, version, err := svg.SplitVersion(svgName)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
 	// Parse query parameters
-	params, err := parseQueryParams(r.URL.Query())
+	params, err := svg.ParseQuery(query)
 	if err != nil {
-		log.Printf("Error parsing parameters for %s: %v", svgName, err)
+		slog.DebugContext(r.Context(), "Invalid parameters", "template", svgName, "error", err)
 		http.Error(w, fmt.Sprintf("Invalid parameters: %v", err), http.StatusBadRequest)
 		return
 	}
-	
-	// Log the processed parameters for debugging
-	log.Printf("Text replacements: %v", params.TextReplacements)
-	log.Printf("Color replacements: %v", params.ColorReplacements)
+
+	if version > 0 {
+		params.Version = version
+	}
+
+	// Fall back to the browser's preferred languages when no lang is given
+	negotiateLanguage := len(params.Languages) == 0
+	if negotiateLanguage {
+		params.Languages = svg.ParseAcceptLanguage(r.Header.Get("Accept-Language"))
+	}
+
+	slog.DebugContext(r.Context(), "Rendering SVG", "template", svgName,
+		"texts", len(params.TextReplacements), "colors", len(params.ColorReplacements))
 
 	// Process the SVG
-	svgData, err := h.processor.ProcessSVG(svgName, params)
+	svgData, err := h.processor.ProcessSVGContext(r.Context(), svgName, params)
+	if errors.Is(err, svg.ErrNotFound) {
+		slog.DebugContext(r.Context(), "SVG not found", "template", svgName, "error", err)
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if errors.Is(err, svg.ErrLimitExceeded) || errors.Is(err, svg.ErrInvalidImage) {
+		slog.DebugContext(r.Context(), "Rejected SVG", "template", svgName, "error", err)
+		http.Error(w, fmt.Sprintf("Invalid parameters: %v", err), http.StatusBadRequest)
+		return
+	}
 	if err != nil {
-		log.Printf("Error processing SVG %s: %v", svgName, err)
+		slog.ErrorContext(r.Context(), "Error processing SVG", "template", svgName, "error", err)
 		http.Error(w, fmt.Sprintf("Error processing SVG: %v", err), http.StatusInternalServerError)
 		return
 	}
-	
-	log.Printf("Successfully processed SVG: %s, size: %d bytes", svgName, len(svgData))
-	
-	// Log first 100 chars of SVG for debugging
-	previewSize := 100
-	if len(svgData) < previewSize {
-		previewSize = len(svgData)
-	}
-	log.Printf("SVG preview: %s...", string(svgData[:previewSize]))
+
+	slog.DebugContext(r.Context(), "Processed SVG", "template", svgName, "bytes", len(svgData))
 
 	// Set content type and other headers
-	w.Header().Set("Content-Type", "image/svg+xml")
+	setSVGHeaders(w)
+	// Pinned renders still depend on the current manifests, catalogs,
+	// fragments and images, so they are revalidated like the latest ones
 	w.Header().Set("Cache-Control", "no-cache")
-	w.Header().Set("X-Content-Type-Options", "nosniff")
-	
+	// Without an explicit lang the catalog depends on the browser's
+	// languages, so caches must keep a render per language
+	if negotiateLanguage && h.processor.HasCatalogs(svgName) {
+		w.Header().Add("Vary", "Accept-Language")
+	}
+
+	// Let clients revalidate with If-None-Match instead of downloading the
+	// same render again
+	tag := etag(svgData)
+	w.Header().Set("ETag", tag)
+	if etagMatches(r.Header.Get("If-None-Match"), tag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
 	// Set appropriate content length
 	w.Header().Set("Content-Length", fmt.Sprintf("%d", len(svgData)))
-	
+
 	// Write the SVG data
 	bytesWritten, err := w.Write(svgData)
 	if err != nil {
-		log.Printf("Error writing SVG response for %s: %v", svgName, err)
+		slog.WarnContext(r.Context(), "Error writing SVG response", "template", svgName, "error", err)
 	} else if bytesWritten != len(svgData) {
-		log.Printf("Warning: Incomplete SVG write for %s: %d of %d bytes", svgName, bytesWritten, len(svgData))
+		slog.WarnContext(r.Context(), "Incomplete SVG write", "template", svgName, "written", bytesWritten, "bytes", len(svgData))
 	}
 }
 
-// parseQueryParams transforms URL query parameters into SVG parameters
-func parseQueryParams(query url.Values) (svg.SVGParams, error) {
-	params := svg.SVGParams{
-		TextReplacements:  make(map[string]string),
-		ColorReplacements: make(map[string]string),
+// ListSVGsHandler returns a list of available SVGs
+func (h *SVGHandler) ListSVGsHandler(w http.ResponseWriter, r *http.Request) {
+	svgs := h.registry.Names()
+	if err := h.registry.Status().LastError; err != nil && len(svgs) == 0 {
+		slog.ErrorContext(r.Context(), "Error listing SVGs", "error", err)
+		http.Error(w, fmt.Sprintf("Error listing SVGs: %v", err), http.StatusInternalServerError)
+		return
 	}
 
-	// Handle width and height
-	if width := query.Get("width"); width != "" {
-		params.Width = width
-	}
-	if height := query.Get("height"); height != "" {
-		params.Height = height
-	}
+	w.Header().Set("Content-Type", "text/plain")
 
-	// Handle text replacements (format: text.element-id=value)
-	for key, values := range query {
-		if strings.HasPrefix(key, "text.") && len(values) > 0 {
-			elementID := strings.TrimPrefix(key, "text.")
-			params.TextReplacements[elementID] = values[0]
-			log.Printf("Adding text replacement: %s -> %s", elementID, values[0])
+	// Top-level templates come first, followed by one group per directory
+	groups := svg.GroupByDirectory(svgs)
+	dirs := make([]string, 0, len(groups))
+	for dir := range groups {
+		dirs = append(dirs, dir)
+	}
+	sort.Strings(dirs)
+	for i, dir := range dirs {
+		if i > 0 {
+			fmt.Fprintln(w)
 		}
-		if strings.HasPrefix(key, "color.") && len(values) > 0 {
-			elementID := strings.TrimPrefix(key, "color.")
-			// Store raw color value without URL decoding (handled in processor)
-			params.ColorReplacements[elementID] = values[0]
-			log.Printf("Adding color replacement: %s -> %s (raw color value)", elementID, values[0])
+		indent := ""
+		if dir != "" {
+			fmt.Fprintf(w, "%s/\n", dir)
+			indent = "  "
+		}
+		for _, name := range groups[dir] {
+			fmt.Fprintf(w, "%s%s\n", indent, name)
+			h.writeHistory(w, indent+"  ", name)
 		}
 	}
-
-	// Handle external URL parameter
-	if externalURL := query.Get("url"); externalURL != "" {
-		// Make sure the URL is properly sanitized to prevent XSS
-		sanitizedURL := strings.Replace(externalURL, "<", "&lt;", -1)
-		sanitizedURL = strings.Replace(sanitizedURL, ">", "&gt;", -1)
-		sanitizedURL = strings.Replace(sanitizedURL, "\"", "&quot;", -1)
-		sanitizedURL = strings.Replace(sanitizedURL, "'", "&#39;", -1)
-		
-		// Use the sanitized URL in the text replacement
-		params.TextReplacements["text-url"] = sanitizedURL
-	}
-
-	return params, nil
 }
 
-// ListSVGsHandler returns a list of available SVGs
-func (h *SVGHandler) ListSVGsHandler(w http.ResponseWriter, r *http.Request) {
-	log.Printf("List SVGs request from: %s", r.RemoteAddr)
-	
-	svgs, err := h.processor.ListAvailableSVGs()
+// writeHistory lists the revisions of a template, newest first, with the
+// name that pins each of them
+func (h *SVGHandler) writeHistory(w http.ResponseWriter, indent, name string) {
+	if h.processor.Revisions == nil {
+		return
+	}
+	history, err := h.processor.Revisions.History(name)
 	if err != nil {
-		log.Printf("Error listing SVGs: %v", err)
-		http.Error(w, fmt.Sprintf("Error listing SVGs: %v", err), http.StatusInternalServerError)
+		slog.Warn("Error reading template history", "template", name, "error", err)
 		return
 	}
+	ext := path.Ext(name)
+	for _, revision := range history {
+		pinned := fmt.Sprintf("%s@%d%s", strings.TrimSuffix(name, ext), revision.Version, ext)
+		fmt.Fprintf(w, "%s%s  %s  %s\n", indent, pinned, revision.Hash[:12], revision.Created.UTC().Format(time.RFC3339))
+	}
+}
 
-	log.Printf("Found %d SVG files", len(svgs))
-	w.Header().Set("Content-Type", "text/plain")
-	for _, svg := range svgs {
-		fmt.Fprintf(w, "%s\n", svg)
+// forbiddenSVG is the placeholder served for unsigned or expired URLs, so
+// pages show why the image is missing
+const forbiddenSVG = `<svg xmlns="http://www.w3.org/2000/svg" width="320" height="80" viewBox="0 0 320 80">
+  <rect x="0.5" y="0.5" width="319" height="79" rx="6" fill="#f1f5f9" stroke="#cbd5e1"/>
+  <text x="160" y="45" font-family="system-ui, sans-serif" font-size="14" text-anchor="middle" fill="#475569">%s</text>
+</svg>
+`
+
+// writeForbiddenSVG answers with the placeholder image and a 403 status
+func writeForbiddenSVG(w http.ResponseWriter, err error) {
+	message := "This image link is not signed"
+	switch err {
+	case svg.ErrSignatureInvalid:
+		message = "This image link has an invalid signature"
+	case svg.ErrSignatureExpired:
+		message = "This image link has expired"
+	case errPresetOverride:
+		message = "This image link can't change its preset"
 	}
-}
\ No newline at end of file
+	setSVGHeaders(w)
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusForbidden)
+	fmt.Fprintf(w, forbiddenSVG, message)
+}
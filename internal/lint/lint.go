@@ -0,0 +1,360 @@
+// Package lint checks SVG templates for problems that break rendering or
+// customization
+package lint
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Severity tells whether a problem breaks a template or only degrades it
+type Severity int
+
+const (
+	// Warning is a problem that is worked around or only affects some renders
+	Warning Severity = iota
+	// Error is a problem that breaks rendering or customization
+	Error
+)
+
+func (s Severity) String() string {
+	if s == Error {
+		return "error"
+	}
+	return "warning"
+}
+
+// Problem is a single lint finding
+type Problem struct {
+	File     string
+	Line     int
+	Severity Severity
+	Rule     string
+	Message  string
+}
+
+func (p Problem) String() string {
+	return fmt.Sprintf("%s:%d: %s: %s (%s)", p.File, p.Line, p.Severity, p.Message, p.Rule)
+}
+
+// Options configures the checks
+type Options struct {
+	// Fonts lists the font families templates may use. Any font is allowed
+	// when it is empty.
+	Fonts []string
+}
+
+// DefaultFonts are the font families available wherever the illustrations
+// are rendered
+var DefaultFonts = []string{
+	"Inter", "Arial", "Helvetica", "system-ui", "sans-serif", "serif", "monospace",
+}
+
+// activeElements can run code or embed HTML. They are matched by local
+// name, whatever their namespace prefix.
+var activeElements = map[string]bool{
+	"script":        true,
+	"foreignObject": true,
+	"iframe":        true,
+	"embed":         true,
+	"object":        true,
+}
+
+// animationElements can change attributes after the document is loaded,
+// including links and event handlers
+var animationElements = map[string]bool{
+	"animate":          true,
+	"set":              true,
+	"animateTransform": true,
+	"animateMotion":    true,
+}
+
+// DefaultOptions returns the options used when none are configured
+func DefaultOptions() Options {
+	return Options{Fonts: DefaultFonts}
+}
+
+// Result holds the problems found in a template and the IDs it defines
+type Result struct {
+	Problems []Problem
+	IDs      map[string]bool
+}
+
+// HasErrors reports whether any problem is an error
+func (r Result) HasErrors() bool {
+	for _, problem := range r.Problems {
+		if problem.Severity == Error {
+			return true
+		}
+	}
+	return false
+}
+
+// SVG checks an SVG document
+func SVG(file string, data []byte, opts Options) Result {
+	result := Result{IDs: make(map[string]bool)}
+	report := func(line int, severity Severity, rule, format string, args ...interface{}) {
+		result.Problems = append(result.Problems, Problem{
+			File:     file,
+			Line:     line,
+			Severity: severity,
+			Rule:     rule,
+			Message:  fmt.Sprintf(format, args...),
+		})
+	}
+
+	fonts := make(map[string]bool, len(opts.Fonts))
+	for _, font := range opts.Fonts {
+		fonts[strings.ToLower(font)] = true
+	}
+
+	idLines := make(map[string]int)
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	decoder.Strict = true
+	root := true
+
+	for {
+		token, err := decoder.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			line, _ := decoder.InputPos()
+			var syntaxErr *xml.SyntaxError
+			if errors.As(err, &syntaxErr) {
+				line = syntaxErr.Line
+			}
+			report(line, Error, "xml", "unparseable XML: %v", err)
+			break
+		}
+
+		start, ok := token.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		// The decoder is at the end of the tag, and attribute values can't
+		// contain "<", so the last one before it opens the tag
+		line := lineAt(data, int64(bytes.LastIndexByte(data[:decoder.InputOffset()], '<')))
+
+		if root {
+			root = false
+			if start.Name.Local != "svg" {
+				report(line, Error, "root", "root element is <%s>, not <svg>", start.Name.Local)
+			} else if attr(start, "viewBox") == "" {
+				report(line, Warning, "viewbox", "root <svg> has no viewBox, so it can't be scaled")
+			}
+		}
+
+		seen := make(map[xml.Name]bool)
+		for _, a := range start.Attr {
+			if seen[a.Name] {
+				severity := Error
+				if a.Name.Local == "xmlns" {
+					// The processor removes duplicate xmlns attributes at render time
+					severity = Warning
+				}
+				report(line, severity, "duplicate-attribute", "duplicate %s attribute on <%s>", qualified(a.Name), start.Name.Local)
+			}
+			seen[a.Name] = true
+
+			if strings.HasPrefix(strings.ToLower(a.Name.Local), "on") {
+				report(line, Error, "script", "event handler %s on <%s>", a.Name.Local, start.Name.Local)
+			}
+			switch name := strings.ToLower(a.Name.Local); {
+			case name == "srcdoc":
+				report(line, Error, "script", "srcdoc attribute on <%s>", start.Name.Local)
+			case (name == "href" || name == "src") && !localReference(a.Value):
+				report(line, Error, "link", "%s on <%s> points outside the document: %q", a.Name.Local, start.Name.Local, a.Value)
+			}
+		}
+
+		id := attr(start, "id")
+		switch {
+		case id == "":
+			if start.Name.Local == "text" {
+				report(line, Warning, "text-id", "<text> without an id can't be customized")
+			}
+		case strings.IndexFunc(id, unicode.IsSpace) >= 0:
+			report(line, Error, "id-space", "id %q contains whitespace", id)
+		case result.IDs[id]:
+			report(line, Error, "duplicate-id", "duplicate id %q, first defined on line %d", id, idLines[id])
+		default:
+			result.IDs[id] = true
+			idLines[id] = line
+		}
+
+		if activeElements[start.Name.Local] {
+			report(line, Error, "script", "<%s> elements are not allowed", start.Name.Local)
+		}
+		if animationElements[start.Name.Local] {
+			target := strings.ToLower(strings.TrimSpace(attr(start, "attributeName")))
+			if target == "href" || target == "xlink:href" || target == "src" || target == "srcdoc" || strings.HasPrefix(target, "on") {
+				report(line, Error, "script", "<%s> animates %s", start.Name.Local, target)
+			}
+		}
+
+		if len(fonts) > 0 {
+			for _, family := range fontFamilies(start) {
+				if !fonts[strings.ToLower(family)] {
+					report(line, Warning, "font", "font %q is not one of %s", family, strings.Join(opts.Fonts, ", "))
+				}
+			}
+		}
+	}
+
+	return result
+}
+
+// Manifest checks that the element IDs a manifest refers to exist in the
+// template it belongs to
+func Manifest(file string, data []byte, ids map[string]bool) []Problem {
+	var problems []Problem
+
+	var manifest struct {
+		Repeat map[string]struct {
+			Text  string   `json:"text"`
+			Shift []string `json:"shift"`
+		} `json:"repeat"`
+	}
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		line := 1
+		var syntaxErr *json.SyntaxError
+		if errors.As(err, &syntaxErr) {
+			line = lineAt(data, syntaxErr.Offset)
+		}
+		return []Problem{{File: file, Line: line, Severity: Error, Rule: "manifest", Message: fmt.Sprintf("invalid manifest: %v", err)}}
+	}
+
+	missing := func(elementID, role string) {
+		problems = append(problems, Problem{
+			File:     file,
+			Line:     lineOf(data, `"`+elementID+`"`),
+			Severity: Error,
+			Rule:     "manifest-id",
+			Message:  fmt.Sprintf("%s %q doesn't match any element", role, elementID),
+		})
+	}
+
+	prototypes := make([]string, 0, len(manifest.Repeat))
+	for id := range manifest.Repeat {
+		prototypes = append(prototypes, id)
+	}
+	sort.Strings(prototypes)
+
+	for _, id := range prototypes {
+		rule := manifest.Repeat[id]
+		if !ids[id] {
+			missing(id, "repeat prototype")
+		}
+		if rule.Text != "" && !ids[rule.Text] {
+			missing(rule.Text, "repeat text element")
+		}
+		for _, shifted := range rule.Shift {
+			if !ids[shifted] {
+				missing(shifted, "shifted element")
+			}
+		}
+	}
+
+	return problems
+}
+
+// Catalog checks that the keys of a JSON translation catalog match elements
+func Catalog(file string, data []byte, ids map[string]bool) []Problem {
+	var catalog map[string]string
+	if err := json.Unmarshal(data, &catalog); err != nil {
+		return []Problem{{File: file, Line: 1, Severity: Error, Rule: "catalog", Message: fmt.Sprintf("invalid catalog: %v", err)}}
+	}
+
+	keys := make([]string, 0, len(catalog))
+	for key := range catalog {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var problems []Problem
+	for _, key := range keys {
+		if !ids[key] {
+			problems = append(problems, Problem{
+				File:     file,
+				Line:     lineOf(data, `"`+key+`"`),
+				Severity: Warning,
+				Rule:     "catalog-id",
+				Message:  fmt.Sprintf("catalog key %q doesn't match any element", key),
+			})
+		}
+	}
+	return problems
+}
+
+// attr returns the value of an unqualified attribute
+func attr(start xml.StartElement, name string) string {
+	for _, a := range start.Attr {
+		if a.Name.Space == "" && a.Name.Local == name {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+// qualified returns an attribute name as written in the document
+func qualified(name xml.Name) string {
+	if name.Space == "" {
+		return name.Local
+	}
+	return name.Space + ":" + name.Local
+}
+
+// fontFamilies returns the font families named by an element's font-family
+// attribute or style
+func fontFamilies(start xml.StartElement) []string {
+	value := attr(start, "font-family")
+	for _, declaration := range strings.Split(attr(start, "style"), ";") {
+		property, v, ok := strings.Cut(declaration, ":")
+		if ok && strings.TrimSpace(property) == "font-family" {
+			value = v
+		}
+	}
+
+	var families []string
+	for _, family := range strings.Split(value, ",") {
+		family = strings.Trim(strings.TrimSpace(family), `"'`)
+		if family != "" {
+			families = append(families, family)
+		}
+	}
+	return families
+}
+
+// lineOf returns the line of the first occurrence of s in data, or 1
+func lineOf(data []byte, s string) int {
+	i := bytes.Index(data, []byte(s))
+	if i < 0 {
+		return 1
+	}
+	return lineAt(data, int64(i))
+}
+
+// lineAt returns the line of a byte offset
+func lineAt(data []byte, offset int64) int {
+	if offset > int64(len(data)) {
+		offset = int64(len(data))
+	}
+	return bytes.Count(data[:offset], []byte("\n")) + 1
+}
+
+// localReference reports whether a link stays inside the rendered document:
+// a fragment, an included fragment or an embedded image
+func localReference(ref string) bool {
+	ref = strings.ToLower(strings.TrimSpace(ref))
+	return strings.HasPrefix(ref, "#") ||
+		strings.HasPrefix(ref, "fragment:") ||
+		strings.HasPrefix(ref, "data:image/")
+}
@@ -0,0 +1,110 @@
+package svg
+
+import (
+	"container/list"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Observer is told about renders, e.g. to collect metrics
+type Observer interface {
+	// RenderDone reports a finished render of a template and its error
+	RenderDone(svgName string, duration time.Duration, err error)
+	// CacheLookup reports whether a render was served from the cache
+	CacheLookup(hit bool)
+}
+
+// RenderCache keeps recently rendered SVGs, evicting the least recently
+// used ones beyond a total size
+type RenderCache struct {
+	// MaxBytes is the total size of the cached renders
+	MaxBytes int
+
+	mu      sync.Mutex
+	order   *list.List
+	entries map[string]*list.Element
+	size    int
+}
+
+// cacheEntry is a cached render
+type cacheEntry struct {
+	key  string
+	data []byte
+}
+
+// NewRenderCache creates a render cache holding up to maxBytes of renders
+func NewRenderCache(maxBytes int) *RenderCache {
+	return &RenderCache{
+		MaxBytes: maxBytes,
+		order:    list.New(),
+		entries:  make(map[string]*list.Element),
+	}
+}
+
+// Get returns a cached render
+func (c *RenderCache) Get(key string) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	element, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	c.order.MoveToFront(element)
+	return element.Value.(*cacheEntry).data, true
+}
+
+// Add caches a render. Renders larger than the cache are not kept.
+func (c *RenderCache) Add(key string, data []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(data) > c.MaxBytes {
+		return
+	}
+	if element, ok := c.entries[key]; ok {
+		c.size -= len(element.Value.(*cacheEntry).data)
+		c.order.Remove(element)
+	}
+	c.entries[key] = c.order.PushFront(&cacheEntry{key: key, data: data})
+	c.size += len(data)
+
+	for c.size > c.MaxBytes {
+		oldest := c.order.Back()
+		entry := oldest.Value.(*cacheEntry)
+		c.order.Remove(oldest)
+		delete(c.entries, entry.key)
+		c.size -= len(entry.data)
+	}
+}
+
+// Purge removes every cached render
+func (c *RenderCache) Purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.order.Init()
+	c.entries = make(map[string]*list.Element)
+	c.size = 0
+}
+
+// Stats returns the number and total size of cached renders
+func (c *RenderCache) Stats() (entries, bytes int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.entries), c.size
+}
+
+// cacheKey identifies a render by its template and parameters. Renders
+// with images aren't cached, since images can change without the
+// templates changing.
+func cacheKey(svgName string, params SVGParams) (string, bool) {
+	if params.Values == nil || len(params.ImageReplacements) > 0 {
+		return "", false
+	}
+	return strings.Join([]string{
+		svgName,
+		CanonicalQuery(params.Values),
+		strings.Join(params.Languages, ","),
+		strconv.Itoa(params.Version),
+	}, "\x00"), true
+}
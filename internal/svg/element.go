@@ -0,0 +1,107 @@
+package svg
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	// tagRegex matches a single start, end or self-closing tag
+	tagRegex = regexp.MustCompile(`<(/?)([a-zA-Z][\w:.-]*)[^>]*?(/?)>`)
+	// idAttrRegex matches an id attribute and captures its value
+	idAttrRegex = regexp.MustCompile(`\bid="([^"]*)"`)
+	// rootTagRegex matches the opening tag of the root svg element
+	rootTagRegex = regexp.MustCompile(`<svg\b[^>]*>`)
+)
+
+// findElement returns the byte range of the element with the given ID,
+// including its start and end tags
+func findElement(svgString, elementID string) (start, end int, ok bool) {
+	startRegex := regexp.MustCompile(`<[a-zA-Z][\w:.-]*\b[^>]*\bid="` + regexp.QuoteMeta(elementID) + `"[^>]*>`)
+	loc := startRegex.FindStringIndex(svgString)
+	if loc == nil {
+		return 0, 0, false
+	}
+	if strings.HasSuffix(svgString[loc[0]:loc[1]], "/>") {
+		return loc[0], loc[1], true
+	}
+
+	// Walk the following tags until the start tag is balanced
+	depth := 1
+	for _, tag := range tagRegex.FindAllStringSubmatchIndex(svgString[loc[1]:], -1) {
+		isClose := tag[3] > tag[2]
+		isSelfClosing := tag[7] > tag[6]
+		switch {
+		case isClose:
+			depth--
+		case !isSelfClosing:
+			depth++
+		}
+		if depth == 0 {
+			return loc[0], loc[1] + tag[1], true
+		}
+	}
+	return 0, 0, false
+}
+
+// renameIDs renames every ID defined in a fragment, along with the url(#id)
+// and href="#id" references to them inside the fragment
+func renameIDs(fragment string, rename func(string) string) string {
+	ids := make(map[string]bool)
+	for _, match := range idAttrRegex.FindAllStringSubmatch(fragment, -1) {
+		ids[match[1]] = true
+	}
+	if len(ids) == 0 {
+		return fragment
+	}
+
+	fragment = idAttrRegex.ReplaceAllStringFunc(fragment, func(attr string) string {
+		id := idAttrRegex.FindStringSubmatch(attr)[1]
+		return `id="` + rename(id) + `"`
+	})
+
+	refRegex := regexp.MustCompile(`(url\(#|href="#)([^)"]*)`)
+	return refRegex.ReplaceAllStringFunc(fragment, func(ref string) string {
+		parts := refRegex.FindStringSubmatch(ref)
+		if !ids[parts[2]] {
+			return ref
+		}
+		return parts[1] + rename(parts[2])
+	})
+}
+
+// rootAttr returns the value of an attribute on the root svg element
+func rootAttr(svgString, name string) (string, bool) {
+	root := rootTagRegex.FindString(svgString)
+	match := regexp.MustCompile(`\s` + regexp.QuoteMeta(name) + `="([^"]*)"`).FindStringSubmatch(root)
+	if match == nil {
+		return "", false
+	}
+	return match[1], true
+}
+
+// setRootAttr sets an attribute on the root svg element, adding it when it
+// doesn't exist yet
+func setRootAttr(svgString, name, value string) string {
+	loc := rootTagRegex.FindStringIndex(svgString)
+	if loc == nil {
+		return svgString
+	}
+	root := svgString[loc[0]:loc[1]]
+	attrRegex := regexp.MustCompile(`(\s)` + regexp.QuoteMeta(name) + `="[^"]*"`)
+	if attrRegex.MatchString(root) {
+		root = attrRegex.ReplaceAllLiteralString(root, ` `+name+`="`+value+`"`)
+	} else {
+		root = strings.Replace(root, "<svg", `<svg `+name+`="`+value+`"`, 1)
+	}
+	return svgString[:loc[0]] + root + svgString[loc[1]:]
+}
+
+// ElementIDs returns the set of IDs defined in an SVG document
+func ElementIDs(svgData []byte) map[string]bool {
+	ids := make(map[string]bool)
+	for _, match := range idAttrRegex.FindAllSubmatch(svgData, -1) {
+		ids[string(match[1])] = true
+	}
+	return ids
+}
@@ -0,0 +1,249 @@
+package svg
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Catalog maps element IDs to translated text for a single language
+type Catalog map[string]string
+
+// catalogPath returns the path of a catalog file for the given template,
+// language and extension, e.g. basic-auth.de.json next to basic-auth.svg
+func catalogPath(svgName, lang, ext string) string {
+	base := strings.TrimSuffix(svgName, path.Ext(svgName))
+	return base + "." + lang + ext
+}
+
+// LoadCatalog loads the translation catalog for a template and language.
+// JSON catalogs take precedence over gettext .po files. It returns nil
+// without an error when no catalog exists for the language.
+func (p *Processor) LoadCatalog(svgName, lang string) (Catalog, error) {
+	lang = normalizeLanguage(lang)
+	if lang == "" {
+		return nil, nil
+	}
+
+	if data, err := p.readFile(catalogPath(svgName, lang, ".json")); err == nil {
+		var catalog Catalog
+		if err := json.Unmarshal(data, &catalog); err != nil {
+			return nil, fmt.Errorf("invalid JSON catalog %s for %s: %w", lang, svgName, err)
+		}
+		return catalog, nil
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("failed to read catalog %s for %s: %w", lang, svgName, err)
+	}
+
+	if data, err := p.readFile(catalogPath(svgName, lang, ".po")); err == nil {
+		catalog, err := parsePO(data)
+		if err != nil {
+			return nil, fmt.Errorf("invalid PO catalog %s for %s: %w", lang, svgName, err)
+		}
+		return catalog, nil
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("failed to read catalog %s for %s: %w", lang, svgName, err)
+	}
+
+	return nil, nil
+}
+
+// HasCatalogs reports whether a template has a translation catalog for any
+// language, in which case its renders depend on the preferred languages
+func (p *Processor) HasCatalogs(svgName string) bool {
+	entries, err := fs.ReadDir(p.FS, path.Dir(svgName))
+	if err != nil {
+		return false
+	}
+	prefix := strings.TrimSuffix(path.Base(svgName), path.Ext(svgName)) + "."
+	for _, entry := range entries {
+		name := entry.Name()
+		if !strings.HasPrefix(name, prefix) || strings.HasSuffix(name, ManifestSuffix) {
+			continue
+		}
+		for _, ext := range []string{".json", ".po"} {
+			if lang, ok := strings.CutSuffix(strings.TrimPrefix(name, prefix), ext); ok && normalizeLanguage(lang) == lang {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// resolveCatalog returns the catalog for the first language in the list that
+// has one, trying the full tag before its primary subtag (de-AT, then de)
+func (p *Processor) resolveCatalog(svgName string, languages []string) (string, Catalog, error) {
+	for _, lang := range languages {
+		candidates := []string{lang}
+		if i := strings.IndexAny(lang, "-_"); i > 0 {
+			candidates = append(candidates, lang[:i])
+		}
+		for _, candidate := range candidates {
+			catalog, err := p.LoadCatalog(svgName, candidate)
+			if err != nil {
+				return "", nil, err
+			}
+			if catalog != nil {
+				return normalizeLanguage(candidate), catalog, nil
+			}
+		}
+	}
+	return "", nil, nil
+}
+
+// applyCatalog returns a copy of params where text elements without an
+// explicit replacement take their text from the catalog. Catalogs hold
+// plain text like the text parameters, so it is escaped the same way.
+func applyCatalog(params SVGParams, catalog Catalog) SVGParams {
+	texts := make(map[string]string, len(params.TextReplacements)+len(catalog))
+	for elementID, text := range catalog {
+		texts[elementID] = html.EscapeString(text)
+	}
+	for elementID, text := range params.TextReplacements {
+		texts[elementID] = text
+	}
+	params.TextReplacements = texts
+	return params
+}
+
+// normalizeLanguage lowercases a language tag and rejects anything that is not
+// a plausible BCP 47 tag, so it can safely be used in a file name
+func normalizeLanguage(lang string) string {
+	lang = strings.ToLower(strings.TrimSpace(lang))
+	if lang == "" || len(lang) > 35 {
+		return ""
+	}
+	for _, r := range lang {
+		if !(r >= 'a' && r <= 'z') && !(r >= '0' && r <= '9') && r != '-' && r != '_' {
+			return ""
+		}
+	}
+	return lang
+}
+
+// ParseAcceptLanguage returns the languages of an Accept-Language header
+// ordered by their quality value, highest first
+func ParseAcceptLanguage(header string) []string {
+	type weighted struct {
+		lang string
+		q    float64
+	}
+
+	var entries []weighted
+	for _, part := range strings.Split(header, ",") {
+		fields := strings.Split(strings.TrimSpace(part), ";")
+		lang := strings.TrimSpace(fields[0])
+		if lang == "" || lang == "*" {
+			continue
+		}
+		q := 1.0
+		for _, field := range fields[1:] {
+			field = strings.TrimSpace(field)
+			if strings.HasPrefix(field, "q=") {
+				if value, err := strconv.ParseFloat(strings.TrimPrefix(field, "q="), 64); err == nil {
+					q = value
+				}
+			}
+		}
+		if q <= 0 {
+			continue
+		}
+		entries = append(entries, weighted{lang: lang, q: q})
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].q > entries[j].q
+	})
+
+	languages := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		languages = append(languages, entry.lang)
+	}
+	return languages
+}
+
+// parsePO reads a gettext .po file into a catalog. The element ID is taken
+// from msgctxt when present and from msgid otherwise. Untranslated entries
+// and the header entry are skipped.
+func parsePO(data []byte) (Catalog, error) {
+	catalog := make(Catalog)
+
+	var ctxt, id, str, ignored string
+	var current *string
+	flush := func() {
+		key := id
+		if ctxt != "" {
+			key = ctxt
+		}
+		if key != "" && str != "" {
+			catalog[key] = str
+		}
+		ctxt, id, str = "", "", ""
+		current = nil
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+
+		switch {
+		case line == "":
+			flush()
+		case strings.HasPrefix(line, "#"):
+			continue
+		case strings.HasPrefix(line, "msgctxt "):
+			if id != "" || str != "" {
+				flush()
+			}
+			current = &ctxt
+			line = strings.TrimPrefix(line, "msgctxt ")
+		case strings.HasPrefix(line, "msgid "):
+			if str != "" {
+				flush()
+			}
+			current = &id
+			line = strings.TrimPrefix(line, "msgid ")
+		case strings.HasPrefix(line, "msgstr "):
+			current = &str
+			line = strings.TrimPrefix(line, "msgstr ")
+		case strings.HasPrefix(line, "msgstr[0] "):
+			// Plural entries use their singular form
+			current = &str
+			line = strings.TrimPrefix(line, "msgstr[0] ")
+		case strings.HasPrefix(line, "msgid_plural "), strings.HasPrefix(line, "msgstr["):
+			current = &ignored
+			line = line[strings.Index(line, " ")+1:]
+		case strings.HasPrefix(line, `"`):
+			// Continuation of the previous keyword
+		default:
+			return nil, fmt.Errorf("line %d: unsupported syntax %q", lineNo, line)
+		}
+
+		if strings.HasPrefix(line, `"`) {
+			if current == nil {
+				return nil, fmt.Errorf("line %d: string without keyword", lineNo)
+			}
+			value, err := strconv.Unquote(line)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", lineNo, err)
+			}
+			*current += value
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	flush()
+
+	return catalog, nil
+}
@@ -0,0 +1,215 @@
+package svg
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Image fit modes, mapped to preserveAspectRatio values
+var imageFits = map[string]string{
+	"cover":   "xMidYMid slice",
+	"contain": "xMidYMid meet",
+	"fill":    "none",
+}
+
+// ErrInvalidImage is returned for image parameters that can't be honored,
+// such as unknown placeholders, assets or hosts
+var ErrInvalidImage = errors.New("invalid image")
+
+// allowedImageTypes are the content types that can be embedded
+var allowedImageTypes = map[string]bool{
+	"image/png":     true,
+	"image/jpeg":    true,
+	"image/gif":     true,
+	"image/webp":    true,
+	"image/svg+xml": true,
+}
+
+// RemoteImagePolicy controls fetching images from URLs. Remote images are
+// disabled when the processor has no policy.
+type RemoteImagePolicy struct {
+	// AllowedHosts lists the hosts images may be fetched from. Entries
+	// starting with "*." match any subdomain.
+	AllowedHosts []string
+	// MaxBytes is the largest image that will be embedded
+	MaxBytes int64
+	// Timeout bounds the whole fetch, including redirects
+	Timeout time.Duration
+}
+
+// allows reports whether the policy allows fetching from a host
+func (r *RemoteImagePolicy) allows(host string) bool {
+	host = strings.ToLower(host)
+	for _, allowed := range r.AllowedHosts {
+		allowed = strings.ToLower(allowed)
+		if host == allowed || (strings.HasPrefix(allowed, "*.") && strings.HasSuffix(host, allowed[1:])) {
+			return true
+		}
+	}
+	return false
+}
+
+// applyImages fills the placeholder elements named in params with the
+// requested images as data URIs, clipped to the placeholder's shape
+func (p *Processor) applyImages(ctx context.Context, svgString string, params SVGParams) (string, error) {
+	for elementID, source := range params.ImageReplacements {
+		fit := params.ImageFits[elementID]
+		if fit == "" {
+			fit = "cover"
+		}
+		aspect, ok := imageFits[fit]
+		if !ok {
+			return "", fmt.Errorf("%w: unknown fit %q for image %s", ErrInvalidImage, fit, elementID)
+		}
+
+		start, end, ok := findElement(svgString, elementID)
+		if !ok {
+			return "", fmt.Errorf("%w: image placeholder %s not found", ErrInvalidImage, elementID)
+		}
+		placeholder := svgString[start:end]
+		if !strings.HasPrefix(placeholder, "<rect") && !strings.HasPrefix(placeholder, "<image") {
+			return "", fmt.Errorf("%w: image placeholder %s must be a rect or image element", ErrInvalidImage, elementID)
+		}
+
+		dataURI, err := p.loadImage(ctx, source)
+		if err != nil {
+			return "", fmt.Errorf("failed to load image for %s: %w", elementID, err)
+		}
+
+		svgString = svgString[:start] + imageSlot(elementID, placeholder, dataURI, aspect) + svgString[end:]
+	}
+	return svgString, nil
+}
+
+// imageSlot builds the markup replacing a placeholder: an image with the
+// placeholder's geometry, clipped to its (rounded) rectangle
+func imageSlot(elementID, placeholder, dataURI, aspect string) string {
+	attrs := make(map[string]string)
+	startTag := placeholder[:strings.Index(placeholder, ">")+1]
+	for _, match := range useAttrRegex.FindAllStringSubmatch(startTag, -1) {
+		attrs[match[1]] = match[2]
+	}
+
+	geometry := ""
+	for _, name := range []string{"x", "y", "width", "height"} {
+		if value, ok := attrs[name]; ok {
+			geometry += fmt.Sprintf(` %s="%s"`, name, value)
+		}
+	}
+	corners := ""
+	for _, name := range []string{"rx", "ry"} {
+		if value, ok := attrs[name]; ok {
+			corners += fmt.Sprintf(` %s="%s"`, name, value)
+		}
+	}
+	group := fmt.Sprintf(`id="%s"`, elementID)
+	if transform, ok := attrs["transform"]; ok {
+		group += fmt.Sprintf(` transform="%s"`, transform)
+	}
+
+	clipID := elementID + "-clip"
+	return fmt.Sprintf(`<g %s><clipPath id="%s"><rect%s%s/></clipPath><image%s href="%s" preserveAspectRatio="%s" clip-path="url(#%s)"/></g>`,
+		group, clipID, geometry, corners, geometry, dataURI, aspect, clipID)
+}
+
+// safeAssetName matches asset names that stay inside the assets directory
+var safeAssetName = regexp.MustCompile(`^[\w-]+(/[\w-]+)*\.[A-Za-z0-9]+$`)
+
+// loadImage returns the image for a source as a data URI. Sources are
+// either names in the assets directory or URLs on an allowed host.
+func (p *Processor) loadImage(ctx context.Context, source string) (string, error) {
+	var data []byte
+	var contentType string
+
+	if strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://") {
+		var err error
+		data, contentType, err = p.fetchImage(ctx, source)
+		if err != nil {
+			return "", err
+		}
+	} else {
+		if p.AssetsPath == "" {
+			return "", fmt.Errorf("%w: local images are not enabled", ErrInvalidImage)
+		}
+		if !safeAssetName.MatchString(source) || strings.Contains(source, "..") {
+			return "", fmt.Errorf("%w: invalid asset name %q", ErrInvalidImage, source)
+		}
+		var err error
+		data, err = os.ReadFile(filepath.Join(p.AssetsPath, filepath.FromSlash(source)))
+		if err != nil {
+			return "", fmt.Errorf("%w: asset %s not found", ErrInvalidImage, source)
+		}
+		contentType = mime.TypeByExtension(filepath.Ext(source))
+	}
+
+	contentType, _, _ = mime.ParseMediaType(contentType)
+	if contentType == "" || contentType == "application/octet-stream" {
+		contentType = http.DetectContentType(data)
+	}
+	if !allowedImageTypes[contentType] {
+		return "", fmt.Errorf("%w: unsupported image type %q", ErrInvalidImage, contentType)
+	}
+
+	return "data:" + contentType + ";base64," + base64.StdEncoding.EncodeToString(data), nil
+}
+
+// fetchImage downloads an image from an allowed host within the size and
+// time limits of the remote image policy. The fetch stops when ctx is done.
+func (p *Processor) fetchImage(ctx context.Context, source string) ([]byte, string, error) {
+	policy := p.RemoteImages
+	if policy == nil {
+		return nil, "", fmt.Errorf("%w: remote images are not enabled", ErrInvalidImage)
+	}
+
+	u, err := url.Parse(source)
+	if err != nil || !policy.allows(u.Hostname()) {
+		return nil, "", fmt.Errorf("%w: host of %q is not allowed", ErrInvalidImage, source)
+	}
+
+	client := &http.Client{
+		Timeout: policy.Timeout,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= 3 || !policy.allows(req.URL.Hostname()) {
+				return fmt.Errorf("%w: redirect to %s is not allowed", ErrInvalidImage, req.URL.Host)
+			}
+			return nil
+		},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, "", fmt.Errorf("%w: %v", ErrInvalidImage, err)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to fetch image: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", fmt.Errorf("failed to fetch image: %s", resp.Status)
+	}
+	if resp.ContentLength > policy.MaxBytes {
+		return nil, "", fmt.Errorf("%w: image is larger than %d bytes", ErrInvalidImage, policy.MaxBytes)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, policy.MaxBytes+1))
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to fetch image: %w", err)
+	}
+	if int64(len(data)) > policy.MaxBytes {
+		return nil, "", fmt.Errorf("%w: image is larger than %d bytes", ErrInvalidImage, policy.MaxBytes)
+	}
+
+	return data, resp.Header.Get("Content-Type"), nil
+}
@@ -0,0 +1,184 @@
+package svg
+
+import (
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// FragmentScheme prefixes use references that are inlined at render time,
+// e.g. <use href="fragment:browser-frame.svg#frame"/>
+const FragmentScheme = "fragment:"
+
+// maxIncludeDepth limits how deeply fragments can include other fragments
+const maxIncludeDepth = 8
+
+var (
+	// fragmentUseRegex matches a use element referencing a fragment
+	fragmentUseRegex = regexp.MustCompile(`<use\b[^>]*\b(?:xlink:)?href="` + FragmentScheme + `[^"]*"[^>]*?(?:/>|>\s*</use>)`)
+	// useAttrRegex matches the attributes of a use element
+	useAttrRegex = regexp.MustCompile(`([\w:-]+)="([^"]*)"`)
+	// idRefRegex matches url(#id) and href="#id" references
+	idRefRegex = regexp.MustCompile(`(?:url\(#|href="#)([^)"]*)`)
+)
+
+// Include describes a fragment inlined into a template from its manifest
+type Include struct {
+	// Src is the fragment to include, e.g. browser-frame.svg#frame
+	Src string `json:"src"`
+	// As is the namespace for the fragment's IDs and parameters. It defaults
+	// to the referenced element ID or the fragment's base name.
+	As string `json:"as,omitempty"`
+	// X and Y offset the fragment
+	X float64 `json:"x,omitempty"`
+	Y float64 `json:"y,omitempty"`
+}
+
+// applyIncludes inlines the manifest includes and every fragment use element.
+// Manifest includes go first in the document so they are drawn behind the
+// template's own content.
+func (p *Processor) applyIncludes(svgString string, includes []Include, depth int) (string, error) {
+	if depth > maxIncludeDepth {
+		return "", fmt.Errorf("fragments nested more than %d levels deep", maxIncludeDepth)
+	}
+
+	if len(includes) > 0 {
+		var inlined strings.Builder
+		for _, include := range includes {
+			fragment, err := p.loadFragment(include.Src, include.As, depth)
+			if err != nil {
+				return "", err
+			}
+			inlined.WriteString(wrapFragment(fragment.namespace, include.X, include.Y, "", fragment.markup))
+		}
+		loc := rootTagRegex.FindStringIndex(svgString)
+		if loc == nil {
+			return "", fmt.Errorf("template has no svg element to include fragments into")
+		}
+		svgString = svgString[:loc[1]] + inlined.String() + svgString[loc[1]:]
+	}
+
+	var firstErr error
+	svgString = fragmentUseRegex.ReplaceAllStringFunc(svgString, func(use string) string {
+		if firstErr != nil {
+			return use
+		}
+		attrs := make(map[string]string)
+		for _, match := range useAttrRegex.FindAllStringSubmatch(use, -1) {
+			attrs[match[1]] = match[2]
+		}
+		src := attrs["href"]
+		if src == "" {
+			src = attrs["xlink:href"]
+		}
+		fragment, err := p.loadFragment(strings.TrimPrefix(src, FragmentScheme), attrs["id"], depth)
+		if err != nil {
+			firstErr = err
+			return use
+		}
+		return wrapFragment(fragment.namespace, toFloat(attrs["x"]), toFloat(attrs["y"]), attrs["transform"], fragment.markup)
+	})
+	if firstErr != nil {
+		return "", firstErr
+	}
+
+	return svgString, nil
+}
+
+// fragment is a piece of markup ready to be inlined
+type fragment struct {
+	namespace string
+	markup    string
+}
+
+// loadFragment reads the fragment referenced by src ("file.svg" or
+// "file.svg#element-id"), resolves its own includes and namespaces its IDs
+func (p *Processor) loadFragment(src, namespace string, depth int) (fragment, error) {
+	file, elementID, _ := strings.Cut(src, "#")
+	if !fs.ValidPath(file) || file == "." {
+		return fragment{}, fmt.Errorf("invalid fragment reference %q", src)
+	}
+
+	data, err := p.readFile(file)
+	if err != nil {
+		return fragment{}, fmt.Errorf("failed to read fragment %s: %w", file, err)
+	}
+	source := string(data)
+
+	var markup string
+	if elementID != "" {
+		start, end, ok := findElement(source, elementID)
+		if !ok {
+			return fragment{}, fmt.Errorf("element %s not found in fragment %s", elementID, file)
+		}
+		markup = source[start:end] + referencedDefs(source, source[start:end])
+	} else {
+		loc := rootTagRegex.FindStringIndex(source)
+		closing := strings.LastIndex(source, "</svg>")
+		if loc == nil || closing < loc[1] {
+			return fragment{}, fmt.Errorf("fragment %s has no svg element", file)
+		}
+		markup = source[loc[1]:closing]
+	}
+
+	// Fragments may include further fragments
+	markup, err = p.applyIncludes(markup, nil, depth+1)
+	if err != nil {
+		return fragment{}, fmt.Errorf("in fragment %s: %w", file, err)
+	}
+
+	if namespace == "" {
+		namespace = elementID
+	}
+	if namespace == "" {
+		namespace = strings.TrimSuffix(path.Base(file), path.Ext(file))
+	}
+
+	return fragment{
+		namespace: namespace,
+		markup:    renameIDs(markup, func(id string) string { return namespace + "." + id }),
+	}, nil
+}
+
+// referencedDefs returns a defs block with the elements outside of markup
+// that markup references, such as filters and gradients
+func referencedDefs(source, markup string) string {
+	defined := make(map[string]bool)
+	for _, match := range idAttrRegex.FindAllStringSubmatch(markup, -1) {
+		defined[match[1]] = true
+	}
+
+	var defs strings.Builder
+	for _, match := range idRefRegex.FindAllStringSubmatch(markup, -1) {
+		id := match[1]
+		if defined[id] {
+			continue
+		}
+		defined[id] = true
+		if start, end, ok := findElement(source, id); ok {
+			defs.WriteString(source[start:end])
+		}
+	}
+	if defs.Len() == 0 {
+		return ""
+	}
+	return "<defs>" + defs.String() + "</defs>"
+}
+
+// wrapFragment places fragment markup in a group carrying its namespace as ID
+func wrapFragment(namespace string, x, y float64, transform, markup string) string {
+	transforms := []string{}
+	if x != 0 || y != 0 {
+		transforms = append(transforms, fmt.Sprintf("translate(%s %s)", formatNumber(x), formatNumber(y)))
+	}
+	if transform != "" {
+		transforms = append(transforms, transform)
+	}
+	attrs := fmt.Sprintf(`id="%s"`, namespace)
+	if len(transforms) > 0 {
+		attrs += fmt.Sprintf(` transform="%s"`, strings.Join(transforms, " "))
+	}
+	return "<g " + attrs + ">" + markup + "</g>"
+}
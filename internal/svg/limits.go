@@ -0,0 +1,82 @@
+package svg
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+)
+
+// ErrLimitExceeded is returned for renders asking for more than a
+// processor's Limits allow
+var ErrLimitExceeded = errors.New("limit exceeded")
+
+// RenderLimits caps what a single render may ask for. A zero value disables
+// the cap.
+type RenderLimits struct {
+	// MaxDimension is the largest width or height
+	MaxDimension float64
+	// MaxTextLength is the longest parameter value, in bytes
+	MaxTextLength int
+	// MaxParams is the largest number of query parameters
+	MaxParams int
+	// MaxOutputBytes is the largest rendered document
+	MaxOutputBytes int
+}
+
+// DefaultLimits returns the caps a processor starts with
+func DefaultLimits() RenderLimits {
+	return RenderLimits{
+		MaxDimension:   4096,
+		MaxTextLength:  1000,
+		MaxParams:      100,
+		MaxOutputBytes: 5 << 20,
+	}
+}
+
+// checkParams enforces the caps on the parameters of a render
+func (l RenderLimits) checkParams(params SVGParams) error {
+	if l.MaxParams > 0 && len(params.Values) > l.MaxParams {
+		return fmt.Errorf("%w: at most %d parameters are allowed, got %d", ErrLimitExceeded, l.MaxParams, len(params.Values))
+	}
+	for key, values := range params.Values {
+		for _, value := range values {
+			if l.MaxTextLength > 0 && len(value) > l.MaxTextLength {
+				return fmt.Errorf("%w: %s is longer than %d bytes", ErrLimitExceeded, key, l.MaxTextLength)
+			}
+		}
+	}
+	for _, dimension := range [][2]string{{"width", params.Width}, {"height", params.Height}} {
+		key, value := dimension[0], dimension[1]
+		if value == "" {
+			continue
+		}
+		size, err := parseDimension(key, value)
+		if err != nil {
+			return err
+		}
+		if l.MaxDimension > 0 && size > l.MaxDimension {
+			return fmt.Errorf("%w: %s can be at most %g, got %s", ErrLimitExceeded, key, l.MaxDimension, value)
+		}
+	}
+	return nil
+}
+
+// parseDimension parses a width or height, which must be a finite positive
+// number. Comparisons with NaN are always false, so it is rejected
+// explicitly.
+func parseDimension(key, value string) (float64, error) {
+	size, err := strconv.ParseFloat(value, 64)
+	if err != nil || math.IsNaN(size) || math.IsInf(size, 0) || size <= 0 {
+		return 0, fmt.Errorf("%s must be a positive number, got %q", key, value)
+	}
+	return size, nil
+}
+
+// checkOutput enforces the cap on the rendered document's size
+func (l RenderLimits) checkOutput(svgData []byte) error {
+	if l.MaxOutputBytes > 0 && len(svgData) > l.MaxOutputBytes {
+		return fmt.Errorf("%w: the rendered SVG is larger than %d bytes", ErrLimitExceeded, l.MaxOutputBytes)
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package svg
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"strings"
+)
+
+// ManifestSuffix is appended to a template's base name to find its manifest,
+// e.g. basic-auth.manifest.json next to basic-auth.svg
+const ManifestSuffix = ".manifest.json"
+
+// Manifest describes template features that can't be expressed in the SVG
+// itself
+type Manifest struct {
+	// Repeat maps the ID of a prototype element to the rule for repeating it
+	Repeat map[string]RepeatRule `json:"repeat,omitempty"`
+	// Include lists fragments inlined at the start of the template
+	Include []Include `json:"include,omitempty"`
+}
+
+// RepeatRule describes how a prototype element is cloned for each item of a
+// list parameter
+type RepeatRule struct {
+	// Param is the query parameter holding the comma separated items
+	Param string `json:"param"`
+	// Axis is the direction the clones are laid out in, "y" (default) or "x"
+	Axis string `json:"axis,omitempty"`
+	// Spacing is the offset between clones in user units
+	Spacing float64 `json:"spacing"`
+	// Text is the ID of the text element inside the prototype that receives
+	// the item text
+	Text string `json:"text,omitempty"`
+	// Shift lists the IDs of elements after the prototype that move along
+	// with the end of the list, such as footers or buttons
+	Shift []string `json:"shift,omitempty"`
+}
+
+// manifestPath returns the path of the manifest for a template
+func manifestPath(svgName string) string {
+	base := strings.TrimSuffix(svgName, path.Ext(svgName))
+	return base + ManifestSuffix
+}
+
+// LoadManifest loads the manifest for a template. Templates without a
+// manifest get an empty one.
+func (p *Processor) LoadManifest(svgName string) (*Manifest, error) {
+	manifest := &Manifest{}
+
+	data, err := p.readFile(manifestPath(svgName))
+	if errors.Is(err, fs.ErrNotExist) {
+		return manifest, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifest for %s: %w", svgName, err)
+	}
+
+	if err := json.Unmarshal(data, manifest); err != nil {
+		return nil, fmt.Errorf("invalid manifest for %s: %w", svgName, err)
+	}
+
+	for id, rule := range manifest.Repeat {
+		if rule.Param == "" {
+			return nil, fmt.Errorf("invalid manifest for %s: repeat %s has no param", svgName, id)
+		}
+		if rule.Axis != "" && rule.Axis != "x" && rule.Axis != "y" {
+			return nil, fmt.Errorf("invalid manifest for %s: repeat %s has unknown axis %q", svgName, id, rule.Axis)
+		}
+	}
+
+	for _, include := range manifest.Include {
+		if include.Src == "" {
+			return nil, fmt.Errorf("invalid manifest for %s: include without src", svgName)
+		}
+	}
+
+	return manifest, nil
+}
@@ -0,0 +1,177 @@
+package svg
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"log/slog"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ErrInvalidElementID is returned for parameters targeting element IDs
+// that aren't XML names
+var ErrInvalidElementID = errors.New("invalid element ID")
+
+// ValidElementID reports whether an element ID can be targeted by
+// parameters: an XML name, optionally namespaced as in frame.text-title
+func ValidElementID(elementID string) bool {
+	return elementIDRegex.MatchString(elementID)
+}
+
+// ValidColor reports whether a color parameter is a hex color, a color name
+// or a color function. A URL encoded # (%23) is accepted, as the processor
+// decodes it.
+func ValidColor(color string) bool {
+	return colorValueRegex.MatchString(strings.ReplaceAll(color, "%23", "#"))
+}
+
+// isColorParam reports whether ParseQuery takes a query parameter as a
+// color, e.g. color.btn or frame.color.btn
+func isColorParam(key string) bool {
+	if strings.HasPrefix(key, "color.") {
+		return true
+	}
+	if strings.HasPrefix(key, "text.") || strings.Contains(key, ".text.") {
+		return false
+	}
+	return strings.Contains(key, ".color.")
+}
+
+// paramElementID returns the element ID a query parameter targets, if any,
+// e.g. text-title for text.text-title and frame.text-url for
+// frame.text.text-url
+func paramElementID(key string) (string, bool) {
+	for _, prefix := range []string{"text.", "color.", "image.", "fit."} {
+		if strings.HasPrefix(key, prefix) {
+			return strings.TrimPrefix(key, prefix), true
+		}
+	}
+	for _, infix := range []string{".text.", ".color."} {
+		if namespace, elementID, ok := strings.Cut(key, infix); ok {
+			return namespace + "." + elementID, true
+		}
+	}
+	return "", false
+}
+
+// ParseQuery transforms URL query parameters into SVG parameters
+func ParseQuery(query url.Values) (SVGParams, error) {
+	params := SVGParams{
+		TextReplacements:  make(map[string]string),
+		ColorReplacements: make(map[string]string),
+		ImageReplacements: make(map[string]string),
+		ImageFits:         make(map[string]string),
+		Values:            query,
+	}
+
+	// IDs end up in patterns matched against the template, so only names
+	// that can be IDs are accepted. Colors go into fill attributes, so they
+	// are checked like in the JSON API.
+	for key, values := range query {
+		if elementID, ok := paramElementID(key); ok && !ValidElementID(elementID) {
+			return params, fmt.Errorf("%w %q in parameter %s", ErrInvalidElementID, elementID, key)
+		}
+		if isColorParam(key) {
+			for _, value := range values {
+				if !ValidColor(value) {
+					return params, fmt.Errorf("invalid color %q in parameter %s", value, key)
+				}
+			}
+		}
+	}
+
+	// Handle width and height. They are capped by the processor's limits
+	// along with the other parameters.
+	for _, key := range []string{"width", "height"} {
+		if value := query.Get(key); value != "" {
+			if _, err := parseDimension(key, value); err != nil {
+				return params, err
+			}
+		}
+	}
+	params.Width = query.Get("width")
+	params.Height = query.Get("height")
+
+	// Handle pinned template versions
+	if version := query.Get("v"); version != "" {
+		v, err := strconv.Atoi(version)
+		if err != nil || v < 1 {
+			return params, fmt.Errorf("invalid version %q", version)
+		}
+		params.Version = v
+	}
+
+	// Handle explicit language selection for translation catalogs
+	if lang := query.Get("lang"); lang != "" {
+		params.Languages = []string{lang}
+	}
+
+	// Handle text replacements (format: text.element-id=value). Values are
+	// plain text, escaped so they can't add markup.
+	for key, values := range query {
+		if strings.HasPrefix(key, "text.") && len(values) > 0 {
+			elementID := strings.TrimPrefix(key, "text.")
+			params.TextReplacements[elementID] = html.EscapeString(values[0])
+			slog.Debug("Adding text replacement", "element", elementID)
+		}
+		if strings.HasPrefix(key, "color.") && len(values) > 0 {
+			elementID := strings.TrimPrefix(key, "color.")
+			// Store raw color value without URL decoding (handled in processor)
+			params.ColorReplacements[elementID] = values[0]
+			slog.Debug("Adding color replacement", "element", elementID, "color", values[0])
+		}
+
+		if strings.HasPrefix(key, "image.") && len(values) > 0 {
+			elementID := strings.TrimPrefix(key, "image.")
+			params.ImageReplacements[elementID] = values[0]
+		}
+		if strings.HasPrefix(key, "fit.") && len(values) > 0 {
+			elementID := strings.TrimPrefix(key, "fit.")
+			params.ImageFits[elementID] = values[0]
+		}
+
+		// Parameters for included fragments are prefixed with their namespace
+		// (format: frame.text.element-id=value targets frame.element-id)
+		if strings.HasPrefix(key, "text.") || strings.HasPrefix(key, "color.") || len(values) == 0 {
+			continue
+		}
+		if namespace, elementID, ok := strings.Cut(key, ".text."); ok {
+			params.TextReplacements[namespace+"."+elementID] = html.EscapeString(values[0])
+		} else if namespace, elementID, ok := strings.Cut(key, ".color."); ok {
+			params.ColorReplacements[namespace+"."+elementID] = values[0]
+		}
+	}
+
+	// Handle external URL parameter
+	if externalURL := query.Get("url"); externalURL != "" {
+		// The URL is shown as text, escaped like other text replacements
+		params.TextReplacements["text-url"] = html.EscapeString(externalURL)
+	}
+
+	return params, nil
+}
+
+// ReferencedIDs returns the sorted element IDs targeted by the parameters
+func (params SVGParams) ReferencedIDs() []string {
+	seen := make(map[string]bool)
+	for _, replacements := range []map[string]string{
+		params.TextReplacements,
+		params.ColorReplacements,
+		params.ImageReplacements,
+		params.ImageFits,
+	} {
+		for elementID := range replacements {
+			seen[elementID] = true
+		}
+	}
+
+	ids := make([]string, 0, len(seen))
+	for elementID := range seen {
+		ids = append(ids, elementID)
+	}
+	sort.Strings(ids)
+	return ids
+}
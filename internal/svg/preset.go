@@ -0,0 +1,191 @@
+package svg
+
+import (
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// presetIDLength is the length of preset IDs unless they collide
+const presetIDLength = 8
+
+// presetIDEncoding encodes preset IDs in lower case without padding
+var presetIDEncoding = base32.NewEncoding("abcdefghijklmnopqrstuvwxyz234567").WithPadding(base32.NoPadding)
+
+// Preset is a template and parameter set saved under a short ID
+type Preset struct {
+	ID string `json:"id"`
+	// Template is the template name, which may pin a version
+	Template string `json:"template"`
+	// Query holds the parameters as a canonical query string
+	Query string `json:"query"`
+	// Created is when the preset was saved
+	Created time.Time `json:"created"`
+}
+
+// Values returns the preset's parameters
+func (p Preset) Values() url.Values {
+	values, _ := url.ParseQuery(p.Query)
+	return values
+}
+
+// ErrPresetStoreFull is returned when saving a new preset to a store that
+// holds its maximum number of presets
+var ErrPresetStoreFull = errors.New("preset store is full")
+
+// PresetStore keeps presets in a JSON file
+type PresetStore struct {
+	// MaxPresets is the most presets the store holds, unlimited when zero
+	MaxPresets int
+	// MaxQueryBytes is the longest canonical query of a preset, unlimited
+	// when zero
+	MaxQueryBytes int
+
+	file string
+
+	mu      sync.Mutex
+	presets map[string]Preset
+}
+
+// OpenPresetStore loads the presets saved in file, which is created when
+// the first preset is saved
+func OpenPresetStore(file string) (*PresetStore, error) {
+	s := &PresetStore{
+		file:    file,
+		presets: make(map[string]Preset),
+	}
+
+	data, err := os.ReadFile(file)
+	if errors.Is(err, fs.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var presets []Preset
+	if err := json.Unmarshal(data, &presets); err != nil {
+		return nil, fmt.Errorf("invalid preset file %s: %w", file, err)
+	}
+	for _, preset := range presets {
+		s.presets[preset.ID] = preset
+	}
+	return s, nil
+}
+
+// Save stores a preset for a template and parameters. IDs are derived from
+// the content, so saving the same preset twice returns the same ID. Queries
+// over MaxQueryBytes fail with ErrLimitExceeded, and new presets fail with
+// ErrPresetStoreFull once the store holds MaxPresets.
+func (s *PresetStore) Save(template string, query url.Values) (Preset, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	preset := Preset{
+		Template: template,
+		Query:    CanonicalQuery(query),
+		Created:  time.Now().UTC(),
+	}
+	if s.MaxQueryBytes > 0 && len(preset.Query) > s.MaxQueryBytes {
+		return Preset{}, fmt.Errorf("%w: preset queries are limited to %d bytes", ErrLimitExceeded, s.MaxQueryBytes)
+	}
+	sum := sha256.Sum256([]byte(preset.Template + "?" + preset.Query))
+	encoded := presetIDEncoding.EncodeToString(sum[:])
+
+	for length := presetIDLength; length <= len(encoded); length++ {
+		id := encoded[:length]
+		existing, ok := s.presets[id]
+		if ok && existing.Template == preset.Template && existing.Query == preset.Query {
+			return existing, nil
+		}
+		if !ok {
+			preset.ID = id
+			break
+		}
+	}
+	if preset.ID == "" {
+		return Preset{}, errors.New("no free preset ID")
+	}
+	if s.MaxPresets > 0 && len(s.presets) >= s.MaxPresets {
+		return Preset{}, fmt.Errorf("%w: it holds %d presets", ErrPresetStoreFull, len(s.presets))
+	}
+
+	s.presets[preset.ID] = preset
+	if err := s.save(); err != nil {
+		delete(s.presets, preset.ID)
+		return Preset{}, err
+	}
+	return preset, nil
+}
+
+// Get returns a preset by ID
+func (s *PresetStore) Get(id string) (Preset, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	preset, ok := s.presets[id]
+	return preset, ok
+}
+
+// List returns all presets, oldest first
+func (s *PresetStore) List() []Preset {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	list := make([]Preset, 0, len(s.presets))
+	for _, preset := range s.presets {
+		list = append(list, preset)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if !list[i].Created.Equal(list[j].Created) {
+			return list[i].Created.Before(list[j].Created)
+		}
+		return list[i].ID < list[j].ID
+	})
+	return list
+}
+
+// Delete removes a preset and reports whether it existed
+func (s *PresetStore) Delete(id string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	preset, ok := s.presets[id]
+	if !ok {
+		return false, nil
+	}
+	delete(s.presets, id)
+	if err := s.save(); err != nil {
+		s.presets[id] = preset
+		return false, err
+	}
+	return true, nil
+}
+
+// save writes all presets to the store's file
+func (s *PresetStore) save() error {
+	list := make([]Preset, 0, len(s.presets))
+	for _, preset := range s.presets {
+		list = append(list, preset)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].ID < list[j].ID
+	})
+	data, err := json.MarshalIndent(list, "", "  ")
+	if err != nil {
+		return err
+	}
+	return WriteFileAtomic(s.file, append(data, '\n'))
+}
+
+// PresetID extracts the preset ID from a preset path such as abc123.svg
+func PresetID(name string) string {
+	return strings.TrimSuffix(name, ".svg")
+}
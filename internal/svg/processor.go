@@ -1,24 +1,54 @@
 package svg
 
 import (
+	"context"
 	"fmt"
-	"log"
+	"io/fs"
+	"log/slog"
 	"os"
-	"path/filepath"
+	"path"
 	"regexp"
 	"strconv"
 	"strings"
+	"time"
 )
 
 // Processor handles SVG processing operations
 type Processor struct {
+	// BasePath is the directory the templates were loaded from, if any
 	BasePath string
+	// FS is the source templates, manifests and catalogs are read from
+	FS fs.FS
+	// AssetsPath is the directory local images are embedded from. Local
+	// images are disabled when it is empty.
+	AssetsPath string
+	// RemoteImages allows embedding images fetched from URLs when set
+	RemoteImages *RemoteImagePolicy
+	// Revisions keeps the revisions of templates, allowing renders of a
+	// pinned version when set
+	Revisions *RevisionStore
+	// Cache keeps recent renders when set
+	Cache *RenderCache
+	// Observer is told about every render when set
+	Observer Observer
+	// Limits caps what a single render may ask for
+	Limits RenderLimits
 }
 
 // NewProcessor creates a new SVG processor with the given base path for SVG files
 func NewProcessor(basePath string) *Processor {
 	return &Processor{
 		BasePath: basePath,
+		FS:       os.DirFS(basePath),
+		Limits:   DefaultLimits(),
+	}
+}
+
+// NewProcessorFS creates a new SVG processor reading SVG files from fsys
+func NewProcessorFS(fsys fs.FS) *Processor {
+	return &Processor{
+		FS:     fsys,
+		Limits: DefaultLimits(),
 	}
 }
 
@@ -32,37 +62,134 @@ type SVGParams struct {
 	Width string
 	// Height of the SVG
 	Height string
+	// Languages in order of preference, used to pick a translation catalog
+	Languages []string
+	// Values holds the raw query parameters, available to Go templates
+	Values map[string][]string
+	// Image replacements map with placeholder ID -> asset name or URL
+	ImageReplacements map[string]string
+	// Image fit modes map with placeholder ID -> cover, contain or fill
+	ImageFits map[string]string
+	// Version pins a revision of the template, 0 renders the latest
+	Version int
 }
 
-// ProcessSVG loads an SVG file and modifies it according to parameters
+// ProcessSVG loads an SVG file and modifies it according to parameters,
+// serving repeated renders from the cache
 func (p *Processor) ProcessSVG(svgName string, params SVGParams) ([]byte, error) {
-	// Construct the path to the SVG file
-	svgPath := filepath.Join(p.BasePath, svgName)
-	
-	// Check if the file exists
-	if _, err := os.Stat(svgPath); os.IsNotExist(err) {
-		return nil, fmt.Errorf("SVG file %s not found", svgName)
+	return p.ProcessSVGContext(context.Background(), svgName, params)
+}
+
+// ProcessSVGContext is ProcessSVG with a context that bounds the fetches of
+// remote images, such as the context of the request asking for the render
+func (p *Processor) ProcessSVGContext(ctx context.Context, svgName string, params SVGParams) ([]byte, error) {
+	// Reject renders asking for more than the processor renders, before
+	// the cache could answer them
+	if err := p.Limits.checkParams(params); err != nil {
+		return nil, err
 	}
-	
-	// Read the SVG file
-	svgData, err := os.ReadFile(svgPath)
+
+	key, cacheable := cacheKey(svgName, params)
+	cacheable = cacheable && p.Cache != nil
+	if cacheable {
+		data, ok := p.Cache.Get(key)
+		if p.Observer != nil {
+			p.Observer.CacheLookup(ok)
+		}
+		if ok {
+			// Callers may modify the result, so they get their own copy
+			return append([]byte(nil), data...), nil
+		}
+	}
+
+	start := time.Now()
+	data, err := p.processSVG(ctx, svgName, params)
+	if p.Observer != nil {
+		p.Observer.RenderDone(svgName, time.Since(start), err)
+	}
+	if err == nil && cacheable {
+		p.Cache.Add(key, append([]byte(nil), data...))
+	}
+	return data, err
+}
+
+// processSVG renders a template
+func (p *Processor) processSVG(ctx context.Context, svgName string, params SVGParams) ([]byte, error) {
+	// Read the SVG file or the pinned revision of it
+	svgData, isTemplate, err := p.loadSource(svgName, params.Version)
+	if err != nil {
+		return nil, err
+	}
+
+	// Execute Go templates with the raw parameters before ID-based replacements
+	if isTemplate {
+		svgData, err = executeTemplate(svgName, svgData, params.Values)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	manifest, err := p.LoadManifest(svgName)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read SVG file: %w", err)
+		return nil, err
 	}
-	
+
+	// Inline included fragments so their elements can be customized too
+	if len(manifest.Include) > 0 || strings.Contains(string(svgData), FragmentScheme) {
+		svgString, err := p.applyIncludes(string(svgData), manifest.Include, 0)
+		if err != nil {
+			return nil, fmt.Errorf("failed to include fragments in %s: %w", svgName, err)
+		}
+		svgData = []byte(svgString)
+	}
+
+	// Clone repeatable prototypes declared in the template's manifest
+	if len(manifest.Repeat) > 0 {
+		svgString, repeatParams, err := applyRepeats(string(svgData), manifest, params)
+		if err != nil {
+			return nil, fmt.Errorf("failed to repeat elements in %s: %w", svgName, err)
+		}
+		svgData, params = []byte(svgString), repeatParams
+	}
+
+	// Fill image placeholders
+	if len(params.ImageReplacements) > 0 {
+		svgString, err := p.applyImages(ctx, string(svgData), params)
+		if err != nil {
+			return nil, err
+		}
+		svgData = []byte(svgString)
+	}
+
+	// Fill in translated text from the catalog for the preferred language
+	if len(params.Languages) > 0 {
+		lang, catalog, err := p.resolveCatalog(svgName, params.Languages)
+		if err != nil {
+			return nil, err
+		}
+		if catalog != nil {
+			slog.Debug("Using catalog", "template", svgName, "lang", lang)
+			params = applyCatalog(params, catalog)
+		}
+	}
+
 	// Parse the SVG to modify it
 	modifiedSVG, err := p.modifySVG(svgData, params)
 	if err != nil {
 		return nil, fmt.Errorf("failed to modify SVG: %w", err)
 	}
-	
+
+	if err := p.Limits.checkOutput(modifiedSVG); err != nil {
+		return nil, err
+	}
+
 	return modifiedSVG, nil
 }
 
 // modifySVG parses and modifies SVG content according to parameters
 func (p *Processor) modifySVG(svgData []byte, params SVGParams) ([]byte, error) {
 	svgString := string(svgData)
-	
+
 	// Step 1: Fix any duplicate xmlns attributes
 	// Ensure only one xmlns attribute exists
 	if strings.Count(svgString, `xmlns="http://www.w3.org/2000/svg"`) > 1 {
@@ -71,50 +198,50 @@ func (p *Processor) modifySVG(svgData []byte, params SVGParams) ([]byte, error)
 		// Add back one occurrence at the right position
 		svgString = strings.Replace(svgString, "<svg", `<svg xmlns="http://www.w3.org/2000/svg"`, 1)
 	}
-	
+
 	// Extract the original dimensions and viewBox
 	originalWidth := "809"
 	originalHeight := "370"
 	widthRegex := regexp.MustCompile(`width="([^"]*)"`)
 	heightRegex := regexp.MustCompile(`height="([^"]*)"`)
 	viewBoxRegex := regexp.MustCompile(`viewBox="([^"]*)"`)
-	
+
 	if widthMatches := widthRegex.FindStringSubmatch(svgString); len(widthMatches) > 1 {
 		originalWidth = widthMatches[1]
 	}
 	if heightMatches := heightRegex.FindStringSubmatch(svgString); len(heightMatches) > 1 {
 		originalHeight = heightMatches[1]
 	}
-	
+
 	// Get current viewBox or create one if it doesn't exist
 	viewBox := "0 0 " + originalWidth + " " + originalHeight
 	if viewBoxMatches := viewBoxRegex.FindStringSubmatch(svgString); len(viewBoxMatches) > 1 {
 		viewBox = viewBoxMatches[1]
 	}
-	
+
 	// Step 2: Handle width and height modifications
 	if params.Width != "" || params.Height != "" {
 		// Store for scaling calculation
 		newWidth := params.Width
 		newHeight := params.Height
-		
+
 		// Replace dimensions while maintaining aspect ratio if only one dimension is specified
 		if params.Width != "" && params.Height == "" {
 			// Calculate height to maintain aspect ratio
 			originalHeightVal, err := strconv.ParseFloat(originalHeight, 64)
 			if err != nil {
-				log.Printf("Error parsing original height: %v", err)
+				slog.Debug("Error parsing original height", "error", err)
 				originalHeightVal = 370
 			}
 			originalWidthVal, err := strconv.ParseFloat(originalWidth, 64)
 			if err != nil {
-				log.Printf("Error parsing original width: %v", err)
+				slog.Debug("Error parsing original width", "error", err)
 				originalWidthVal = 809
 			}
 			aspectRatio := originalHeightVal / originalWidthVal
 			widthVal, err := strconv.ParseFloat(params.Width, 64)
 			if err != nil {
-				log.Printf("Error parsing width parameter: %v", err)
+				slog.Debug("Error parsing width parameter", "error", err)
 				widthVal = 400
 			}
 			heightVal := widthVal * aspectRatio
@@ -123,37 +250,37 @@ func (p *Processor) modifySVG(svgData []byte, params SVGParams) ([]byte, error)
 			// Calculate width to maintain aspect ratio
 			originalWidthVal, err := strconv.ParseFloat(originalWidth, 64)
 			if err != nil {
-				log.Printf("Error parsing original width: %v", err)
+				slog.Debug("Error parsing original width", "error", err)
 				originalWidthVal = 809
 			}
 			originalHeightVal, err := strconv.ParseFloat(originalHeight, 64)
 			if err != nil {
-				log.Printf("Error parsing original height: %v", err)
+				slog.Debug("Error parsing original height", "error", err)
 				originalHeightVal = 370
 			}
 			aspectRatio := originalWidthVal / originalHeightVal
 			heightVal, err := strconv.ParseFloat(params.Height, 64)
 			if err != nil {
-				log.Printf("Error parsing height parameter: %v", err)
+				slog.Debug("Error parsing height parameter", "error", err)
 				heightVal = 200
 			}
 			widthVal := heightVal * aspectRatio
 			newWidth = fmt.Sprintf("%.0f", widthVal)
 		}
-		
+
 		// Apply changes
 		if newWidth != "" {
 			widthPattern := `width="[^"]*"`
 			svgString = regexp.MustCompile(widthPattern).ReplaceAllString(svgString, `width="`+newWidth+`"`)
 			params.Width = newWidth
 		}
-		
+
 		if newHeight != "" {
 			heightPattern := `height="[^"]*"`
 			svgString = regexp.MustCompile(heightPattern).ReplaceAllString(svgString, `height="`+newHeight+`"`)
 			params.Height = newHeight
 		}
-		
+
 		// Always ensure viewBox is set to maintain proportions
 		viewBoxPattern := `viewBox="[^"]*"`
 		if viewBoxRegex.MatchString(svgString) {
@@ -163,57 +290,47 @@ func (p *Processor) modifySVG(svgData []byte, params SVGParams) ([]byte, error)
 			svgString = strings.Replace(svgString, "<svg", `<svg viewBox="`+viewBox+`"`, 1)
 		}
 	}
-	
+
 	// Step 3: Handle text replacements
 	for elementID, newText := range params.TextReplacements {
-		log.Printf("Replacing text for element ID: %s with: %s", elementID, newText)
-		
-		// Dump current SVG content for debugging
-		log.Printf("Current SVG content (first 200 chars): %s", svgString[:min(200, len(svgString))])
-		
+		slog.Debug("Replacing text", "element", elementID)
+
 		// First try with the specific structure of our SVG that uses tspan elements
 		// This is a very specific pattern for the exact structure of our example SVG
-		tspanSpecificPattern := `(<text id="` + elementID + `"[^>]*>[ \t\n\r]*<tspan[^>]*>)[^<]*(</tspan>)`
+		tspanSpecificPattern := `(<text id="` + regexp.QuoteMeta(elementID) + `"[^>]*>[ \t\n\r]*<tspan[^>]*>)[^<]*(</tspan>)`
 		if matches := regexp.MustCompile(tspanSpecificPattern).FindStringSubmatch(svgString); len(matches) > 0 {
-			log.Printf("Found specific tspan pattern for %s. Match: %s", elementID, matches[0])
-			newSvgString := regexp.MustCompile(tspanSpecificPattern).ReplaceAllString(svgString, "${1}"+newText+"${2}")
+			newSvgString := regexp.MustCompile(tspanSpecificPattern).ReplaceAllString(svgString, "${1}"+literalReplacement(newText)+"${2}")
 			if newSvgString != svgString {
-				log.Printf("Text replacement succeeded with specific tspan pattern")
+				slog.Debug("Text replacement succeeded with specific tspan pattern", "element", elementID)
 				svgString = newSvgString
 				continue
 			}
 		}
-		
+
 		// Fall back to more general patterns
-		log.Printf("Trying more general patterns for element ID: %s", elementID)
-		
+
 		// Try a pattern for direct text content
-		directPattern := `(<text id="` + elementID + `"[^>]*>)([^<]*)(</text>)`
+		directPattern := `(<text id="` + regexp.QuoteMeta(elementID) + `"[^>]*>)([^<]*)(</text>)`
 		if matches := regexp.MustCompile(directPattern).FindStringSubmatch(svgString); len(matches) > 0 {
-			log.Printf("Found direct text pattern for %s. Match: %s", elementID, matches[0])
-			newSvgString := regexp.MustCompile(directPattern).ReplaceAllString(svgString, "${1}"+newText+"${3}")
+			newSvgString := regexp.MustCompile(directPattern).ReplaceAllString(svgString, "${1}"+literalReplacement(newText)+"${3}")
 			if newSvgString != svgString {
-				log.Printf("Text replacement succeeded with direct pattern")
+				slog.Debug("Text replacement succeeded with direct pattern", "element", elementID)
 				svgString = newSvgString
 				continue
 			}
 		}
-		
+
 		// Try one more pattern for nested elements that's common in SVGs
-		log.Printf("Trying complex pattern for element ID: %s", elementID)
 		svgBeforeComplexPattern := svgString // save for comparison
-		
+
 		// This hacky approach is more likely to work with real SVGs
 		// We find the text element, extract its content, and make a targeted replacement
-		re := regexp.MustCompile(`<text[^>]*id="` + elementID + `"[^>]*>(.*?)</text>`)
+		re := regexp.MustCompile(`<text[^>]*id="` + regexp.QuoteMeta(elementID) + `"[^>]*>(.*?)</text>`)
 		matches := re.FindStringSubmatch(svgString)
 		if len(matches) > 0 {
-			log.Printf("Found text element with id=%s: %s", elementID, matches[0])
-			
 			// See if there's a tspan inside
 			tspanContent := regexp.MustCompile(`<tspan[^>]*>(.*?)</tspan>`).FindStringSubmatch(matches[1])
 			if len(tspanContent) > 0 {
-				log.Printf("Found tspan content: %s", tspanContent[1])
 				// Replace just the text content inside the tspan
 				newTextElement := strings.Replace(matches[0], tspanContent[1], newText, 1)
 				svgString = strings.Replace(svgString, matches[0], newTextElement, 1)
@@ -222,25 +339,25 @@ func (p *Processor) modifySVG(svgData []byte, params SVGParams) ([]byte, error)
 				newTextElement := strings.Replace(matches[0], matches[1], newText, 1)
 				svgString = strings.Replace(svgString, matches[0], newTextElement, 1)
 			}
-			
+
 			if svgString != svgBeforeComplexPattern {
-				log.Printf("Text replacement succeeded with complex pattern")
+				slog.Debug("Text replacement succeeded with complex pattern", "element", elementID)
 				continue
 			}
 		}
-		
-		log.Printf("WARNING: No pattern matched for %s", elementID)
+
+		slog.Debug("No text pattern matched", "element", elementID)
 	}
-	
+
 	// Step 4: Handle color replacements
 	for elementID, newColor := range params.ColorReplacements {
-		log.Printf("Applying color replacement for element ID: %s with color: %s", elementID, newColor)
-		
+		slog.Debug("Replacing color", "element", elementID, "color", newColor)
+
 		// URL decode the color if it uses hex notation with %23 instead of #
 		if strings.Contains(newColor, "%23") {
 			newColor = strings.Replace(newColor, "%23", "#", -1)
 		}
-		
+
 		// Special handling for elements with known SVG structure
 		switch elementID {
 		case "page-background":
@@ -248,9 +365,8 @@ func (p *Processor) modifySVG(svgData []byte, params SVGParams) ([]byte, error)
 			fillPattern := `<rect id="page-background"[^>]*fill="[^"]*"`
 			if regexp.MustCompile(fillPattern).MatchString(svgString) {
 				svgString = regexp.MustCompile(fillPattern).ReplaceAllString(
-					svgString, 
-					`<rect id="page-background" width="809" height="370" fill="`+newColor+`"`)
-				log.Printf("Updated page background color to: %s", newColor)
+					svgString,
+					`<rect id="page-background" width="809" height="370" fill="`+literalReplacement(newColor)+`"`)
 				continue
 			}
 		case "prompt-background":
@@ -259,11 +375,10 @@ func (p *Processor) modifySVG(svgData []byte, params SVGParams) ([]byte, error)
 			if regexp.MustCompile(fillPattern).MatchString(svgString) {
 				replacement := regexp.MustCompile(`fill="[^"]*"`).ReplaceAllString(
 					regexp.MustCompile(fillPattern).FindString(svgString),
-					`fill="`+newColor+`"`)
-				svgString = strings.Replace(svgString, 
+					`fill="`+literalReplacement(newColor)+`"`)
+				svgString = strings.Replace(svgString,
 					regexp.MustCompile(fillPattern).FindString(svgString),
 					replacement, 1)
-				log.Printf("Updated prompt background color to: %s", newColor)
 				continue
 			}
 		case "btn-background_2":
@@ -272,76 +387,66 @@ func (p *Processor) modifySVG(svgData []byte, params SVGParams) ([]byte, error)
 			if regexp.MustCompile(fillPattern).MatchString(svgString) {
 				replacement := regexp.MustCompile(`fill="[^"]*"`).ReplaceAllString(
 					regexp.MustCompile(fillPattern).FindString(svgString),
-					`fill="`+newColor+`"`)
-				svgString = strings.Replace(svgString, 
+					`fill="`+literalReplacement(newColor)+`"`)
+				svgString = strings.Replace(svgString,
 					regexp.MustCompile(fillPattern).FindString(svgString),
 					replacement, 1)
-				log.Printf("Updated sign-in button color to: %s", newColor)
 				continue
 			}
 		}
-		
+
 		// For any other element, try a generic approach
-		
+
 		// First, try to find elements with the exact ID that have a fill attribute
-		fillPattern := `(<[^>]*id="` + elementID + `"[^>]*fill=")[^"]*(")`
+		fillPattern := `(<[^>]*id="` + regexp.QuoteMeta(elementID) + `"[^>]*fill=")[^"]*(")`
 		replaced := false
 		if regexp.MustCompile(fillPattern).MatchString(svgString) {
 			oldSvg := svgString
-			svgString = regexp.MustCompile(fillPattern).ReplaceAllString(svgString, "${1}"+newColor+"${2}")
+			svgString = regexp.MustCompile(fillPattern).ReplaceAllString(svgString, "${1}"+literalReplacement(newColor)+"${2}")
 			replaced = (oldSvg != svgString)
 			if replaced {
-				log.Printf("Updated fill attribute for element with ID: %s", elementID)
+				slog.Debug("Updated fill attribute", "element", elementID)
 				continue
 			}
 		}
-		
+
 		// If that didn't work, try to find elements with the exact ID and add a fill attribute
 		if !replaced {
-			exactIdPattern := `(<[^>]*id="` + elementID + `"[^>]*)(>)`
+			exactIdPattern := `(<[^>]*id="` + regexp.QuoteMeta(elementID) + `"[^>]*)(>)`
 			if matches := regexp.MustCompile(exactIdPattern).FindStringSubmatch(svgString); len(matches) > 0 {
-				log.Printf("Found element with ID %s, adding fill attribute", elementID)
 				oldSvg := svgString
-				svgString = regexp.MustCompile(exactIdPattern).ReplaceAllString(svgString, "${1} fill=\""+newColor+"\"${2}")
+				svgString = regexp.MustCompile(exactIdPattern).ReplaceAllString(svgString, "${1} fill=\""+literalReplacement(newColor)+"\"${2}")
 				replaced = (oldSvg != svgString)
 				if replaced {
-					log.Printf("Added fill attribute to element with ID: %s", elementID)
+					slog.Debug("Added fill attribute", "element", elementID)
 					continue
 				}
 			}
 		}
-		
+
 		// Last resort: try to find texts with the specified ID and change their fill color
 		if !replaced {
-			textPattern := `(<text id="` + elementID + `"[^>]*)(fill="[^"]*")?([^>]*>)`
+			textPattern := `(<text id="` + regexp.QuoteMeta(elementID) + `"[^>]*)(fill="[^"]*")?([^>]*>)`
 			if matches := regexp.MustCompile(textPattern).FindStringSubmatch(svgString); len(matches) > 0 {
 				if matches[2] != "" {
 					// Replace existing fill
-					svgString = strings.Replace(svgString, matches[0], 
+					svgString = strings.Replace(svgString, matches[0],
 						strings.Replace(matches[0], matches[2], `fill="`+newColor+`"`, 1), 1)
 				} else {
 					// Add fill attribute
-					svgString = strings.Replace(svgString, matches[0], 
-						matches[1] + ` fill="`+newColor+`"` + matches[3], 1)
+					svgString = strings.Replace(svgString, matches[0],
+						matches[1]+` fill="`+newColor+`"`+matches[3], 1)
 				}
-				log.Printf("Updated text color for element ID: %s", elementID)
+				slog.Debug("Updated text color", "element", elementID)
 				continue
 			}
 		}
-		
+
 		if !replaced {
-			log.Printf("No suitable element found for color replacement with ID: %s", elementID)
+			slog.Debug("No element found for color replacement", "element", elementID)
 		}
 	}
-	
-	// Utility function to get min of two integers
-	min := func(a, b int) int {
-		if a < b {
-			return a
-		}
-		return b
-	}
-	
+
 	// Apply final scaling transformations for better proportional scaling
 	if params.Width != "" || params.Height != "" {
 		// Add a preserveAspectRatio attribute to maintain proportions
@@ -349,29 +454,60 @@ func (p *Processor) modifySVG(svgData []byte, params SVGParams) ([]byte, error)
 			svgString = strings.Replace(svgString, "<svg", `<svg preserveAspectRatio="xMidYMid meet"`, 1)
 		}
 	}
-	
+
 	// Ensure final SVG is valid
 	svgString = strings.TrimSpace(svgString)
-	
-	// Preview SVG for debugging
-	previewLen := min(100, len(svgString))
-	log.Printf("Final SVG preview (first %d chars): %s", previewLen, svgString[:previewLen])
+
 	return []byte(svgString), nil
 }
 
-// ListAvailableSVGs returns a list of available SVG files
+// ListAvailableSVGs returns the names of the available SVG files, including
+// those in subdirectories (e.g. auth/basic.svg), in lexical order
 func (p *Processor) ListAvailableSVGs() ([]string, error) {
-	entries, err := os.ReadDir(p.BasePath)
+	var svgFiles []string
+	err := fs.WalkDir(p.FS, ".", func(name string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		// Skip hidden and private directories such as revision stores
+		if entry.IsDir() {
+			if name != "." && (strings.HasPrefix(entry.Name(), ".") || strings.HasPrefix(entry.Name(), "_")) {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if strings.HasSuffix(name, ".svg") {
+			svgFiles = append(svgFiles, name)
+		}
+		// Go templates are served under their .svg name
+		if strings.HasSuffix(name, ".svg"+TemplateExt) {
+			svgFiles = append(svgFiles, strings.TrimSuffix(name, TemplateExt))
+		}
+		return nil
+	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to read SVG directory: %w", err)
 	}
-	
-	var svgFiles []string
-	for _, entry := range entries {
-		if !entry.IsDir() && strings.HasSuffix(entry.Name(), ".svg") {
-			svgFiles = append(svgFiles, entry.Name())
+
+	return svgFiles, nil
+}
+
+// GroupByDirectory groups template names by their directory. Top-level
+// templates are grouped under "".
+func GroupByDirectory(names []string) map[string][]string {
+	groups := make(map[string][]string)
+	for _, name := range names {
+		dir := path.Dir(name)
+		if dir == "." {
+			dir = ""
 		}
+		groups[dir] = append(groups[dir], name)
 	}
-	
-	return svgFiles, nil
-}
\ No newline at end of file
+	return groups
+}
+
+// literalReplacement escapes $ in a value used in a regexp replacement
+// template, so it is inserted as is
+func literalReplacement(value string) string {
+	return strings.ReplaceAll(value, "$", "$$")
+}
@@ -0,0 +1,43 @@
+package svg
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// RasterizeCommand is the command used to convert SVG to PNG. It must read
+// SVG on stdin and write PNG to stdout. librsvg's rsvg-convert is used by
+// default because it renders text, filters and embedded images faithfully.
+var RasterizeCommand = []string{"rsvg-convert", "--format", "png"}
+
+// RasterizeTimeout bounds a single conversion
+var RasterizeTimeout = 30 * time.Second
+
+// RasterizePNG converts a rendered SVG to PNG using RasterizeCommand
+func RasterizePNG(ctx context.Context, svgData []byte) ([]byte, error) {
+	if len(RasterizeCommand) == 0 {
+		return nil, fmt.Errorf("PNG output is not configured")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, RasterizeTimeout)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, RasterizeCommand[0], RasterizeCommand[1:]...)
+	cmd.Stdin = bytes.NewReader(svgData)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("failed to rasterize SVG with %s: %w: %s", RasterizeCommand[0], err, msg)
+		}
+		return nil, fmt.Errorf("failed to rasterize SVG with %s: %w", RasterizeCommand[0], err)
+	}
+
+	return stdout.Bytes(), nil
+}
@@ -0,0 +1,261 @@
+package svg
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"sort"
+	"sync"
+	"time"
+)
+
+// TemplateInfo describes a template known to the registry
+type TemplateInfo struct {
+	// Name is the name the template is served under, e.g. auth/basic.svg
+	Name string `json:"name"`
+	// Size is the size of the template source in bytes
+	Size int `json:"size"`
+	// Hash is the SHA-256 of the template source
+	Hash string `json:"hash"`
+	// Version is the template's latest revision, if revisions are kept
+	Version int `json:"version,omitempty"`
+}
+
+// Registry keeps an index of the available templates, reloaded on demand
+// or by polling the template source
+type Registry struct {
+	processor *Processor
+
+	mu          sync.RWMutex
+	templates   map[string]TemplateInfo
+	fingerprint string
+	lastReload  time.Time
+	lastErr     error
+	reloads     int
+	failures    int
+	invalid     map[string]error
+}
+
+// RegistryStatus describes the registry's reloads
+type RegistryStatus struct {
+	// Templates is the number of indexed templates
+	Templates int
+	// LastReload is when the registry was last reloaded
+	LastReload time.Time
+	// LastError is the error of the last reload, if it failed
+	LastError error
+	// Reloads and Failures count all reloads and the failed ones
+	Reloads  int
+	Failures int
+	// Invalid holds the error of each template that doesn't render
+	// without parameters, as of the last change to the source
+	Invalid map[string]error
+}
+
+// NewRegistry creates a registry for the templates of a processor. It is
+// empty until the first Reload.
+func NewRegistry(processor *Processor) *Registry {
+	return &Registry{
+		processor: processor,
+		templates: make(map[string]TemplateInfo),
+	}
+}
+
+// Reload rebuilds the index from the template source. The previous index is
+// kept when the source can't be read. When any file in the source changed,
+// cached renders are dropped and the templates are checked again.
+func (r *Registry) Reload() error {
+	templates, err := r.scan()
+	var fingerprint string
+	if err == nil {
+		fingerprint, err = r.fingerprintFiles()
+	}
+	var invalid map[string]error
+	changed := err == nil && fingerprint != r.currentFingerprint()
+	if changed {
+		invalid = r.validate(templates)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.lastReload = time.Now()
+	r.lastErr = err
+	r.reloads++
+	if err != nil {
+		r.failures++
+		return err
+	}
+	r.templates = templates
+	if changed {
+		r.fingerprint = fingerprint
+		r.invalid = invalid
+		if r.processor.Cache != nil {
+			r.processor.Cache.Purge()
+		}
+	}
+	return nil
+}
+
+// currentFingerprint returns the fingerprint of the indexed source
+func (r *Registry) currentFingerprint() string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.fingerprint
+}
+
+// validate renders each template without parameters, bypassing the render
+// cache, and checks that the result is well-formed XML
+func (r *Registry) validate(templates map[string]TemplateInfo) map[string]error {
+	invalid := make(map[string]error)
+	for name := range templates {
+		data, err := r.processor.processSVG(context.Background(), name, SVGParams{})
+		if err == nil {
+			err = checkXML(data)
+		}
+		if err != nil {
+			invalid[name] = err
+		}
+	}
+	return invalid
+}
+
+// checkXML reports whether data is a well-formed XML document
+func checkXML(data []byte) error {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	decoder.Strict = true
+	for {
+		_, err := decoder.Token()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("invalid XML: %w", err)
+		}
+	}
+}
+
+// fingerprintFiles hashes the names, sizes and modification times of all
+// files in the template source, including manifests, catalogs and
+// fragments
+func (r *Registry) fingerprintFiles() (string, error) {
+	hash := sha256.New()
+	err := fs.WalkDir(r.processor.FS, ".", func(name string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return err
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(hash, "%s %d %d\n", name, info.Size(), info.ModTime().UnixNano())
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// scan reads every template, records its size and hash, and stores a new
+// revision when it changed
+func (r *Registry) scan() (map[string]TemplateInfo, error) {
+	names, err := r.processor.ListAvailableSVGs()
+	if err != nil {
+		return nil, err
+	}
+
+	templates := make(map[string]TemplateInfo, len(names))
+	for _, name := range names {
+		source, isTemplate, err := r.processor.loadSource(name, 0)
+		if err != nil {
+			return nil, err
+		}
+		sum := sha256.Sum256(source)
+		info := TemplateInfo{
+			Name: name,
+			Size: len(source),
+			Hash: hex.EncodeToString(sum[:]),
+		}
+		if r.processor.Revisions != nil {
+			revision, err := r.processor.Revisions.Record(name, source, isTemplate)
+			if err != nil {
+				return nil, err
+			}
+			info.Version = revision.Version
+		}
+		templates[name] = info
+	}
+	return templates, nil
+}
+
+// List returns the indexed templates ordered by name
+func (r *Registry) List() []TemplateInfo {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	list := make([]TemplateInfo, 0, len(r.templates))
+	for _, info := range r.templates {
+		list = append(list, info)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Name < list[j].Name
+	})
+	return list
+}
+
+// Names returns the names of the indexed templates in order
+func (r *Registry) Names() []string {
+	list := r.List()
+	names := make([]string, len(list))
+	for i, info := range list {
+		names[i] = info.Name
+	}
+	return names
+}
+
+// Get returns the index entry of a template
+func (r *Registry) Get(name string) (TemplateInfo, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	info, ok := r.templates[name]
+	return info, ok
+}
+
+// Status returns the size of the index and the outcome of the reloads
+func (r *Registry) Status() RegistryStatus {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return RegistryStatus{
+		Templates:  len(r.templates),
+		LastReload: r.lastReload,
+		LastError:  r.lastErr,
+		Reloads:    r.reloads,
+		Failures:   r.failures,
+		Invalid:    r.invalid,
+	}
+}
+
+// Watch reloads the registry every interval until the context is done, so
+// templates added or changed outside the admin API are picked up
+func (r *Registry) Watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := r.Reload(); err != nil {
+				slog.Error("Error reloading templates", "error", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package svg
+
+import (
+	"fmt"
+	"html"
+	"log/slog"
+	"strconv"
+	"strings"
+)
+
+// applyRepeats clones every prototype element from the manifest once per
+// item of its list parameter. Clones get their IDs suffixed with their
+// 1-based position, are offset along the rule's axis and have their text
+// element filled with the item. The root size and viewBox grow to fit.
+func applyRepeats(svgString string, manifest *Manifest, params SVGParams) (string, SVGParams, error) {
+	for prototypeID, rule := range manifest.Repeat {
+		items := listParam(params.Values, rule.Param)
+		if items == nil {
+			// Without the parameter the prototype is rendered as designed
+			continue
+		}
+
+		start, end, ok := findElement(svgString, prototypeID)
+		if !ok {
+			return "", params, fmt.Errorf("repeat prototype %s not found", prototypeID)
+		}
+		prototype := svgString[start:end]
+
+		var clones strings.Builder
+		for i, item := range items {
+			suffix := "-" + strconv.Itoa(i+1)
+			clone := renameIDs(prototype, func(id string) string { return id + suffix })
+
+			clones.WriteString(translate(rule.Axis, float64(i)*rule.Spacing, clone))
+
+			if rule.Text != "" {
+				params = withDefaultText(params, rule.Text+suffix, html.EscapeString(item))
+			}
+		}
+		svgString = svgString[:start] + clones.String() + svgString[end:]
+
+		// Grow (or shrink) the canvas by the space taken by the extra clones
+		// and move the elements that follow the list
+		delta := float64(len(items)-1) * rule.Spacing
+		svgString = growCanvas(svgString, rule.Axis, delta)
+		for _, elementID := range rule.Shift {
+			shiftStart, shiftEnd, ok := findElement(svgString, elementID)
+			if !ok {
+				return "", params, fmt.Errorf("shifted element %s not found", elementID)
+			}
+			svgString = svgString[:shiftStart] + translate(rule.Axis, delta, svgString[shiftStart:shiftEnd]) + svgString[shiftEnd:]
+		}
+		slog.Debug("Repeated element", "element", prototypeID, "count", len(items))
+	}
+
+	return svgString, params, nil
+}
+
+// translate wraps markup in a group offset along the given axis
+func translate(axis string, offset float64, markup string) string {
+	dx, dy := 0.0, offset
+	if axis == "x" {
+		dx, dy = offset, 0
+	}
+	return fmt.Sprintf(`<g transform="translate(%s %s)">%s</g>`, formatNumber(dx), formatNumber(dy), markup)
+}
+
+// listParam returns the comma separated items of a query parameter, or nil
+// when the parameter is absent
+func listParam(values map[string][]string, name string) []string {
+	vals, ok := values[name]
+	if !ok || len(vals) == 0 {
+		return nil
+	}
+	items := []string{}
+	for _, v := range vals {
+		for _, item := range strings.Split(v, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+	}
+	return items
+}
+
+// withDefaultText returns params with a text replacement added unless the
+// element already has one. The map is copied so callers' maps stay intact.
+func withDefaultText(params SVGParams, elementID, text string) SVGParams {
+	if _, exists := params.TextReplacements[elementID]; exists {
+		return params
+	}
+	texts := make(map[string]string, len(params.TextReplacements)+1)
+	for id, t := range params.TextReplacements {
+		texts[id] = t
+	}
+	texts[elementID] = text
+	params.TextReplacements = texts
+	return params
+}
+
+// growCanvas adds delta user units to the root height (axis y) or width
+// (axis x) and to the matching viewBox dimension
+func growCanvas(svgString, axis string, delta float64) string {
+	attr, viewBoxIndex := "height", 3
+	if axis == "x" {
+		attr, viewBoxIndex = "width", 2
+	}
+
+	if value, ok := rootAttr(svgString, attr); ok {
+		if size, err := strconv.ParseFloat(value, 64); err == nil {
+			svgString = setRootAttr(svgString, attr, formatNumber(size+delta))
+		}
+	}
+
+	if viewBox, ok := rootAttr(svgString, "viewBox"); ok {
+		fields := strings.Fields(strings.ReplaceAll(viewBox, ",", " "))
+		if len(fields) == 4 {
+			if size, err := strconv.ParseFloat(fields[viewBoxIndex], 64); err == nil {
+				fields[viewBoxIndex] = formatNumber(size + delta)
+				svgString = setRootAttr(svgString, "viewBox", strings.Join(fields, " "))
+			}
+		}
+	}
+
+	return svgString
+}
+
+// formatNumber formats a coordinate without trailing zeros
+func formatNumber(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
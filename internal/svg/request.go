@@ -0,0 +1,168 @@
+package svg
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// RenderRequest is a render described as JSON, as accepted by the render
+// API, instead of a query string
+type RenderRequest struct {
+	// Texts maps element IDs to replacement text
+	Texts map[string]string `json:"texts,omitempty"`
+	// Colors maps element IDs to fill colors, e.g. #0ea5e9
+	Colors map[string]string `json:"colors,omitempty"`
+	// Images maps placeholder IDs to asset names or URLs
+	Images map[string]string `json:"images,omitempty"`
+	// Fits maps placeholder IDs to cover, contain or fill
+	Fits map[string]string `json:"fits,omitempty"`
+	// Width and Height set the rendered size; zero keeps the template's
+	Width  float64 `json:"width,omitempty"`
+	Height float64 `json:"height,omitempty"`
+	// Lang selects the translation catalog
+	Lang string `json:"lang,omitempty"`
+	// Version pins a revision of the template
+	Version int `json:"version,omitempty"`
+	// Params holds raw query parameters, such as lists for repeated
+	// elements or namespaced fragment parameters
+	Params map[string]string `json:"params,omitempty"`
+	// Format is the output format, svg (default) or png
+	Format string `json:"format,omitempty"`
+}
+
+// FieldErrors maps request fields, e.g. "colors.btn-background", to what is
+// wrong with them
+type FieldErrors map[string]string
+
+var (
+	// colorValueRegex matches hex colors, color names and color functions
+	colorValueRegex = regexp.MustCompile(`^(#[0-9a-fA-F]{3,4}|#[0-9a-fA-F]{6}|#[0-9a-fA-F]{8}|[a-zA-Z]+|(?:rgb|rgba|hsl|hsla)\([0-9.,%\s]+\))$`)
+	// elementIDRegex matches usable element IDs: XML names, which may
+	// contain dots for namespaced fragment IDs
+	elementIDRegex = regexp.MustCompile(`^[A-Za-z_][\w.:-]*$`)
+)
+
+// Validate checks the request against the limits of the processor that
+// renders it and returns the problems by field
+func (req RenderRequest) Validate(limits RenderLimits) FieldErrors {
+	errs := make(FieldErrors)
+
+	checkIDs := func(field string, values map[string]string) {
+		for elementID := range values {
+			if !elementIDRegex.MatchString(elementID) {
+				errs[field+"."+elementID] = "is not a valid element ID"
+			}
+		}
+	}
+	checkLengths := func(field string, values map[string]string) {
+		for key, value := range values {
+			if limits.MaxTextLength > 0 && len(value) > limits.MaxTextLength {
+				errs[field+"."+key] = fmt.Sprintf("can be at most %d bytes long", limits.MaxTextLength)
+			}
+		}
+	}
+	checkLengths("texts", req.Texts)
+	checkLengths("params", req.Params)
+
+	checkIDs("texts", req.Texts)
+	checkIDs("colors", req.Colors)
+	checkIDs("images", req.Images)
+	checkIDs("fits", req.Fits)
+
+	for elementID, color := range req.Colors {
+		if !colorValueRegex.MatchString(color) {
+			errs["colors."+elementID] = "must be a hex color, color name or rgb()/hsl() value"
+		}
+	}
+	for elementID, fit := range req.Fits {
+		if _, ok := imageFits[fit]; !ok {
+			errs["fits."+elementID] = "must be cover, contain or fill"
+		}
+	}
+	for field, size := range map[string]float64{"width": req.Width, "height": req.Height} {
+		if size < 0 {
+			errs[field] = "must be a positive number"
+		} else if limits.MaxDimension > 0 && size > limits.MaxDimension {
+			errs[field] = fmt.Sprintf("can be at most %g", limits.MaxDimension)
+		}
+	}
+	if req.Version < 0 {
+		errs["version"] = "must be a positive number"
+	}
+	switch strings.ToLower(req.Format) {
+	case "", "svg", "png":
+	default:
+		errs["format"] = "must be svg or png"
+	}
+	for key, value := range req.Params {
+		if key == "" {
+			errs["params"] = "keys can't be empty"
+		} else if elementID, ok := paramElementID(key); ok && !ValidElementID(elementID) {
+			errs["params."+key] = "is not a valid element ID"
+		} else if isColorParam(key) && !ValidColor(value) {
+			errs["params."+key] = "must be a hex color, color name or rgb()/hsl() value"
+		}
+	}
+	return errs
+}
+
+// Query converts the request to the query parameters of an equivalent /ui/
+// URL. Raw params are applied first, so the typed fields take precedence.
+func (req RenderRequest) Query() url.Values {
+	query := make(url.Values)
+	for key, value := range req.Params {
+		query.Set(key, value)
+	}
+	for prefix, values := range map[string]map[string]string{
+		"text.":  req.Texts,
+		"color.": req.Colors,
+		"image.": req.Images,
+		"fit.":   req.Fits,
+	} {
+		for elementID, value := range values {
+			query.Set(prefix+elementID, value)
+		}
+	}
+	if req.Width > 0 {
+		query.Set("width", strconv.FormatFloat(req.Width, 'f', -1, 64))
+	}
+	if req.Height > 0 {
+		query.Set("height", strconv.FormatFloat(req.Height, 'f', -1, 64))
+	}
+	if req.Lang != "" {
+		query.Set("lang", req.Lang)
+	}
+	if req.Version > 0 {
+		query.Set("v", strconv.Itoa(req.Version))
+	}
+	return query
+}
+
+// UnknownIDs returns errors for the element IDs the request targets that
+// are missing from the rendered document's IDs
+func (req RenderRequest) UnknownIDs(ids map[string]bool) FieldErrors {
+	errs := make(FieldErrors)
+	fields := []string{"texts", "colors", "images", "fits"}
+	for i, values := range []map[string]string{req.Texts, req.Colors, req.Images, req.Fits} {
+		for elementID := range values {
+			if !ids[elementID] {
+				errs[fields[i]+"."+elementID] = "no element with this ID in the template"
+			}
+		}
+	}
+	return errs
+}
+
+// Fields returns the fields with errors in order
+func (errs FieldErrors) Fields() []string {
+	fields := make([]string, 0, len(errs))
+	for field := range errs {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	return fields
+}
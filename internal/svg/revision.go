@@ -0,0 +1,186 @@
+package svg
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Revision is an immutable copy of a template's source
+type Revision struct {
+	// Version numbers the revisions of a template, starting at 1
+	Version int `json:"version"`
+	// Hash is the SHA-256 of the revision's source
+	Hash string `json:"hash"`
+	// Created is when the revision was recorded
+	Created time.Time `json:"created"`
+	// Template tells whether the revision is a Go template
+	Template bool `json:"template"`
+}
+
+// RevisionStore keeps every revision of the templates it is shown, one file
+// per revision named {version}-{hash}.svg, or .svg.tmpl for Go templates,
+// in a directory per template
+type RevisionStore struct {
+	// Dir is the directory revisions are stored in
+	Dir string
+
+	mu sync.Mutex
+}
+
+// NewRevisionStore creates a revision store in dir
+func NewRevisionStore(dir string) *RevisionStore {
+	return &RevisionStore{Dir: dir}
+}
+
+// Record stores source as the latest revision of a template unless it is
+// already the latest, and returns the latest revision
+func (s *RevisionStore) Record(name string, source []byte, isTemplate bool) (Revision, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sum := sha256.Sum256(source)
+	hash := hex.EncodeToString(sum[:])
+
+	history, err := s.history(name)
+	if err != nil {
+		return Revision{}, err
+	}
+	if len(history) > 0 && history[0].Hash == hash && history[0].Template == isTemplate {
+		return history[0], nil
+	}
+
+	revision := Revision{Version: 1, Hash: hash, Created: time.Now(), Template: isTemplate}
+	if len(history) > 0 {
+		revision.Version = history[0].Version + 1
+	}
+	if err := WriteFileAtomic(s.revisionFile(name, revision), source); err != nil {
+		return Revision{}, err
+	}
+	return revision, nil
+}
+
+// History returns the revisions of a template, newest first
+func (s *RevisionStore) History(name string) ([]Revision, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.history(name)
+}
+
+// Read returns the source of a revision of a template
+func (s *RevisionStore) Read(name string, version int) ([]byte, Revision, error) {
+	history, err := s.History(name)
+	if err != nil {
+		return nil, Revision{}, err
+	}
+	for _, revision := range history {
+		if revision.Version == version {
+			source, err := os.ReadFile(s.revisionFile(name, revision))
+			return source, revision, err
+		}
+	}
+	return nil, Revision{}, fmt.Errorf("version %d of %s %w", version, name, ErrNotFound)
+}
+
+// history lists the revision files of a template
+func (s *RevisionStore) history(name string) ([]Revision, error) {
+	if !fs.ValidPath(name) {
+		return nil, fmt.Errorf("invalid SVG name %s", name)
+	}
+	entries, err := os.ReadDir(filepath.Join(s.Dir, filepath.FromSlash(name)))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var history []Revision
+	for _, entry := range entries {
+		fileName := entry.Name()
+		isTemplate := strings.HasSuffix(fileName, ".svg"+TemplateExt)
+		base := strings.TrimSuffix(strings.TrimSuffix(fileName, TemplateExt), ".svg")
+		versionText, hash, ok := strings.Cut(base, "-")
+		version, err := strconv.Atoi(versionText)
+		if !ok || err != nil || len(hash) != 2*sha256.Size || entry.IsDir() {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return nil, err
+		}
+		history = append(history, Revision{
+			Version:  version,
+			Hash:     hash,
+			Created:  info.ModTime(),
+			Template: isTemplate,
+		})
+	}
+	sort.Slice(history, func(i, j int) bool {
+		return history[i].Version > history[j].Version
+	})
+	return history, nil
+}
+
+// revisionFile returns the path a revision is stored at
+func (s *RevisionStore) revisionFile(name string, revision Revision) string {
+	fileName := fmt.Sprintf("%d-%s.svg", revision.Version, revision.Hash)
+	if revision.Template {
+		fileName += TemplateExt
+	}
+	return filepath.Join(s.Dir, filepath.FromSlash(name), fileName)
+}
+
+// SplitVersion splits a pinned template name such as auth/basic@3.svg into
+// the template name and version. Names without a version are returned
+// unchanged with version 0.
+func SplitVersion(svgName string) (string, int, error) {
+	dir, file := path.Split(svgName)
+	ext := path.Ext(file)
+	base, versionText, ok := strings.Cut(strings.TrimSuffix(file, ext), "@")
+	if !ok {
+		return svgName, 0, nil
+	}
+	version, err := strconv.Atoi(versionText)
+	if err != nil || version < 1 {
+		return "", 0, fmt.Errorf("invalid version %q in %s", versionText, svgName)
+	}
+	return dir + base + ext, version, nil
+}
+
+// WriteFileAtomic writes a file through a temporary file in the same
+// directory, so readers never see a partially written file
+func WriteFileAtomic(file string, data []byte) error {
+	dir := filepath.Dir(file)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(dir, ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(0o644); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), file)
+}
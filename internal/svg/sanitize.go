@@ -0,0 +1,183 @@
+package svg
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+)
+
+var (
+	// activeElements can run code or embed HTML. They are matched by local
+	// name, so namespace prefixes such as x:foreignObject don't hide them.
+	activeElements = map[string]bool{
+		"script":        true,
+		"foreignObject": true,
+		"iframe":        true,
+		"embed":         true,
+		"object":        true,
+	}
+	// animationElements can change attributes after the document is loaded
+	animationElements = map[string]bool{
+		"animate":          true,
+		"set":              true,
+		"animateTransform": true,
+		"animateMotion":    true,
+	}
+	// linkAttrs hold URLs that are loaded or followed
+	linkAttrs = map[string]bool{
+		"href":       true,
+		"src":        true,
+		"action":     true,
+		"formaction": true,
+	}
+	// cssURLRegex matches url() references in attributes and styles
+	cssURLRegex = regexp.MustCompile(`(?i)url\(\s*['"]?([^'")]*)['"]?\s*\)`)
+	// cssImportRegex matches @import rules in style elements
+	cssImportRegex = regexp.MustCompile(`(?i)@import[^;]*;?`)
+)
+
+// Sanitize removes active and external content from an uploaded template:
+// script, foreignObject and other embedding elements, animations of links
+// and event handlers, event handler attributes, DOCTYPE declarations (whose
+// entities could smuggle markup), and references to anything but the
+// document itself, embedded images and fragments. The template is parsed as
+// XML; markup that isn't touched is kept byte for byte.
+func Sanitize(svgData []byte) ([]byte, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(svgData))
+	decoder.Strict = true
+
+	var out bytes.Buffer
+	// RawToken doesn't check that tags are balanced, which dropping elements
+	// with their content relies on, so open elements are tracked here
+	var open []xml.Name
+	skipDepth := 0
+	offset := int64(0)
+	for {
+		token, err := decoder.RawToken()
+		if errors.Is(err, io.EOF) {
+			if len(open) > 0 {
+				return nil, fmt.Errorf("invalid XML: <%s> is not closed", rawName(open[len(open)-1]))
+			}
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid XML: %w", err)
+		}
+		raw := svgData[offset:decoder.InputOffset()]
+		offset = decoder.InputOffset()
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			open = append(open, t.Name)
+			if skipDepth > 0 || isActiveElement(t) {
+				skipDepth++
+				continue
+			}
+			if attrs, changed := sanitizeAttrs(t.Attr); changed {
+				raw = startTag(t.Name, attrs, bytes.HasSuffix(bytes.TrimSpace(raw), []byte("/>")))
+			}
+		case xml.EndElement:
+			if len(open) == 0 || open[len(open)-1] != t.Name {
+				return nil, fmt.Errorf("invalid XML: unexpected </%s>", rawName(t.Name))
+			}
+			open = open[:len(open)-1]
+			if skipDepth > 0 {
+				skipDepth--
+				continue
+			}
+		case xml.Directive:
+			continue
+		default:
+			if skipDepth > 0 {
+				continue
+			}
+		}
+		out.Write(raw)
+	}
+
+	svgString := cssURLRegex.ReplaceAllStringFunc(out.String(), func(ref string) string {
+		if strings.HasPrefix(strings.TrimSpace(cssURLRegex.FindStringSubmatch(ref)[1]), "#") {
+			return ref
+		}
+		return "none"
+	})
+	svgString = cssImportRegex.ReplaceAllString(svgString, "")
+
+	return []byte(svgString), nil
+}
+
+// isActiveElement reports whether an element is removed with its content:
+// active elements, and animations that change links or event handlers,
+// which could point them at scripts after sanitizing
+func isActiveElement(start xml.StartElement) bool {
+	if activeElements[start.Name.Local] {
+		return true
+	}
+	if !animationElements[start.Name.Local] {
+		return false
+	}
+	for _, attr := range start.Attr {
+		if attr.Name.Local == "attributeName" {
+			// xlink:href animates href, so the prefix doesn't matter
+			target := strings.ToLower(strings.TrimSpace(attr.Value))
+			target = target[strings.LastIndex(target, ":")+1:]
+			return linkAttrs[target] || strings.HasPrefix(target, "on") || target == "srcdoc"
+		}
+	}
+	return false
+}
+
+// sanitizeAttrs drops event handlers and non-local links from the
+// attributes of an element, reporting whether any was dropped
+func sanitizeAttrs(attrs []xml.Attr) ([]xml.Attr, bool) {
+	kept := make([]xml.Attr, 0, len(attrs))
+	for _, attr := range attrs {
+		local := strings.ToLower(attr.Name.Local)
+		switch {
+		case strings.HasPrefix(local, "on"), local == "srcdoc":
+			continue
+		case linkAttrs[local] && !isLocalReference(attr.Value):
+			continue
+		}
+		kept = append(kept, attr)
+	}
+	return kept, len(kept) != len(attrs)
+}
+
+// startTag writes a start tag with the names as written in the document,
+// as RawToken leaves prefixes in Name.Space
+func startTag(name xml.Name, attrs []xml.Attr, selfClosing bool) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("<" + rawName(name))
+	for _, attr := range attrs {
+		buf.WriteString(" " + rawName(attr.Name) + `="`)
+		xml.EscapeText(&buf, []byte(attr.Value))
+		buf.WriteString(`"`)
+	}
+	if selfClosing {
+		buf.WriteString("/>")
+	} else {
+		buf.WriteString(">")
+	}
+	return buf.Bytes()
+}
+
+// rawName returns a name with its prefix, if any
+func rawName(name xml.Name) string {
+	if name.Space == "" {
+		return name.Local
+	}
+	return name.Space + ":" + name.Local
+}
+
+// isLocalReference reports whether a link stays inside the rendered document
+func isLocalReference(ref string) bool {
+	ref = strings.TrimSpace(ref)
+	return strings.HasPrefix(ref, "#") ||
+		strings.HasPrefix(ref, FragmentScheme) ||
+		strings.HasPrefix(strings.ToLower(ref), "data:image/")
+}
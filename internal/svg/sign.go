@@ -0,0 +1,97 @@
+package svg
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// Query parameters of signed URLs
+const (
+	// SignatureParam holds the signature of a URL
+	SignatureParam = "sig"
+	// ExpiresParam holds the Unix time a signed URL expires at
+	ExpiresParam = "exp"
+)
+
+// Errors returned when verifying signed URLs
+var (
+	ErrSignatureMissing = errors.New("the URL is not signed")
+	ErrSignatureInvalid = errors.New("the URL signature is invalid")
+	ErrSignatureExpired = errors.New("the signed URL has expired")
+)
+
+// Signer signs and verifies URLs with an HMAC-SHA256 over the template name
+// and the canonical query, so signed URLs can't be changed to render other
+// text
+type Signer struct {
+	secret []byte
+}
+
+// NewSigner creates a signer with a server secret
+func NewSigner(secret string) *Signer {
+	return &Signer{secret: []byte(secret)}
+}
+
+// Sign adds a signature to the query of a URL for a template. URLs expire
+// at expires unless it is the zero time.
+func (s *Signer) Sign(svgName string, query url.Values, expires time.Time) url.Values {
+	signed := make(url.Values, len(query)+2)
+	for key, values := range query {
+		signed[key] = append([]string(nil), values...)
+	}
+	signed.Del(SignatureParam)
+	signed.Del(ExpiresParam)
+	if !expires.IsZero() {
+		signed.Set(ExpiresParam, strconv.FormatInt(expires.Unix(), 10))
+	}
+	signed.Set(SignatureParam, s.signature(svgName, signed))
+	return signed
+}
+
+// Verify checks the signature and expiry of a request for a template
+func (s *Signer) Verify(svgName string, query url.Values, now time.Time) error {
+	sig := query.Get(SignatureParam)
+	if sig == "" {
+		return ErrSignatureMissing
+	}
+	if !hmac.Equal([]byte(sig), []byte(s.signature(svgName, query))) {
+		return ErrSignatureInvalid
+	}
+	if exp := query.Get(ExpiresParam); exp != "" {
+		expires, err := strconv.ParseInt(exp, 10, 64)
+		if err != nil {
+			return ErrSignatureInvalid
+		}
+		if now.Unix() > expires {
+			return ErrSignatureExpired
+		}
+	}
+	return nil
+}
+
+// signature computes the signature of a template name and query, ignoring
+// any signature already in the query
+func (s *Signer) signature(svgName string, query url.Values) string {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte(svgName))
+	mac.Write([]byte{'?'})
+	mac.Write([]byte(CanonicalQuery(query)))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// CanonicalQuery encodes a query without its signature, with keys sorted,
+// so equivalent URLs produce the same string
+func CanonicalQuery(query url.Values) string {
+	canonical := make(url.Values, len(query))
+	for key, values := range query {
+		if key != SignatureParam {
+			canonical[key] = values
+		}
+	}
+	return canonical.Encode()
+}
@@ -0,0 +1,136 @@
+package svg
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"sort"
+	"strings"
+)
+
+// ErrNotFound is returned for templates and versions that don't exist
+var ErrNotFound = errors.New("not found")
+
+// overlayFS serves files from the first layer that has them, so templates
+// in an upper layer replace those of the same name below it
+type overlayFS []fs.FS
+
+// OverlayFS combines template sources. Layers earlier in the list take
+// precedence, and layers that don't exist (such as a missing directory)
+// are skipped.
+func OverlayFS(layers ...fs.FS) fs.FS {
+	return overlayFS(layers)
+}
+
+// Open opens the named file from the first layer that has it
+func (o overlayFS) Open(name string) (fs.File, error) {
+	for _, layer := range o {
+		file, err := layer.Open(name)
+		if err == nil {
+			return file, nil
+		}
+		if !errors.Is(err, fs.ErrNotExist) {
+			return nil, err
+		}
+	}
+	return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+}
+
+// ReadDir merges the directory listings of all layers
+func (o overlayFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	seen := make(map[string]bool)
+	var entries []fs.DirEntry
+	found := false
+	for _, layer := range o {
+		layerEntries, err := fs.ReadDir(layer, name)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		found = true
+		for _, entry := range layerEntries {
+			if !seen[entry.Name()] {
+				seen[entry.Name()] = true
+				entries = append(entries, entry)
+			}
+		}
+	}
+	if !found {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrNotExist}
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Name() < entries[j].Name()
+	})
+	return entries, nil
+}
+
+// readFile reads a file from the template source. Names are slash separated
+// and relative to the source's root.
+func (p *Processor) readFile(name string) ([]byte, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+	return fs.ReadFile(p.FS, name)
+}
+
+// Source returns the unprocessed source of a template
+func (p *Processor) Source(svgName string) ([]byte, error) {
+	name, _, err := p.resolveTemplate(svgName)
+	if err != nil {
+		return nil, err
+	}
+	return p.readFile(name)
+}
+
+// loadSource reads the source of a template, falling back to a Go template
+// of the same name, or the source of a revision when a version is given.
+// Revisions stay available after the template is deleted.
+func (p *Processor) loadSource(svgName string, version int) ([]byte, bool, error) {
+	if version > 0 {
+		if p.Revisions == nil {
+			return nil, false, fmt.Errorf("versions of %s are not available", svgName)
+		}
+		if !validName(svgName) {
+			return nil, false, fmt.Errorf("invalid SVG name %s", svgName)
+		}
+		source, revision, err := p.Revisions.Read(svgName, version)
+		if err != nil {
+			return nil, false, err
+		}
+		return source, revision.Template, nil
+	}
+
+	svgPath, isTemplate, err := p.resolveTemplate(svgName)
+	if err != nil {
+		return nil, false, err
+	}
+	source, err := p.readFile(svgPath)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to read SVG file: %w", err)
+	}
+	return source, isTemplate, nil
+}
+
+// resolveTemplate returns the file a template name is served from, which is
+// the SVG itself or a Go template of the same name
+func (p *Processor) resolveTemplate(svgName string) (string, bool, error) {
+	if !validName(svgName) {
+		return "", false, fmt.Errorf("invalid SVG name %s", svgName)
+	}
+	if _, err := fs.Stat(p.FS, svgName); err == nil {
+		return svgName, false, nil
+	}
+	if _, err := fs.Stat(p.FS, svgName+TemplateExt); err == nil {
+		return svgName + TemplateExt, true, nil
+	}
+	return "", false, fmt.Errorf("SVG file %s %w", svgName, ErrNotFound)
+}
+
+// validName reports whether a template name may be served. fs.ValidPath
+// rejects absolute paths and ".." elements, so names can't escape the
+// template source. Hidden directories are never served.
+func validName(svgName string) bool {
+	return fs.ValidPath(svgName) && svgName != "." && !strings.HasPrefix(svgName, ".") && !strings.Contains(svgName, "/.")
+}
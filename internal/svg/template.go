@@ -0,0 +1,120 @@
+package svg
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"strconv"
+	"strings"
+)
+
+// TemplateExt is the extension of SVG templates that are executed as Go
+// html/template files before any ID-based replacements are applied.
+// A request for menu.svg is served from menu.svg.tmpl when it exists.
+const TemplateExt = ".tmpl"
+
+// TemplateValue holds the values of a query parameter. Values are split on
+// commas, so items=Home,Settings can be ranged over, while printing the value
+// joins it back together.
+type TemplateValue []string
+
+// String returns the value as it appeared in the query
+func (v TemplateValue) String() string {
+	return strings.Join(v, ",")
+}
+
+// TemplateData is the data passed to SVG templates
+type TemplateData struct {
+	// Params holds the query parameters by name
+	Params map[string]TemplateValue
+}
+
+// templateFuncs are the helper functions available inside SVG templates
+var templateFuncs = template.FuncMap{
+	// default returns def when value is empty: {{ .Params.title | default "Sign in" }}
+	"default": func(def string, value interface{}) interface{} {
+		switch v := value.(type) {
+		case nil:
+			return def
+		case string:
+			if v == "" {
+				return def
+			}
+		case TemplateValue:
+			if len(v) == 0 {
+				return def
+			}
+		}
+		return value
+	},
+	// bool parses a flag, as any non-empty value, "false" included, is true
+	// for if: {{ if bool .Params.showCancel }}. Values strconv.ParseBool
+	// doesn't accept, and missing parameters, are false.
+	"bool": func(value interface{}) bool {
+		if value == nil {
+			return false
+		}
+		b, _ := strconv.ParseBool(strings.TrimSpace(fmt.Sprint(value)))
+		return b
+	},
+	// split splits a string on a separator
+	"split": func(s interface{}, sep string) []string {
+		return strings.Split(fmt.Sprint(s), sep)
+	},
+	// add and mul help position repeated elements: y="{{ add 100 (mul $i 40) }}"
+	"add": func(a, b interface{}) float64 { return toFloat(a) + toFloat(b) },
+	"mul": func(a, b interface{}) float64 { return toFloat(a) * toFloat(b) },
+}
+
+// toFloat converts a template argument to a number, treating anything
+// unparseable as zero
+func toFloat(value interface{}) float64 {
+	switch v := value.(type) {
+	case int:
+		return float64(v)
+	case float64:
+		return v
+	default:
+		f, _ := strconv.ParseFloat(strings.TrimSpace(fmt.Sprint(v)), 64)
+		return f
+	}
+}
+
+// newTemplateData builds template data from raw query values
+func newTemplateData(values map[string][]string) TemplateData {
+	data := TemplateData{Params: make(map[string]TemplateValue, len(values))}
+	for key, vals := range values {
+		var value TemplateValue
+		for _, v := range vals {
+			if v == "" {
+				continue
+			}
+			value = append(value, strings.Split(v, ",")...)
+		}
+		data.Params[key] = value
+	}
+	return data
+}
+
+// executeTemplate runs an SVG template with the raw request parameters.
+// html/template applies contextual escaping, so parameter values can't
+// inject markup into the document.
+func executeTemplate(name string, source []byte, values map[string][]string) ([]byte, error) {
+	// Missing parameters evaluate to an empty value instead of "<no value>"
+	tmpl, err := template.New(name).Funcs(templateFuncs).Option("missingkey=zero").Parse(string(source))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse template %s: %w", name, err)
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, newTemplateData(values)); err != nil {
+		return nil, fmt.Errorf("failed to execute template %s: %w", name, err)
+	}
+	return buf.Bytes(), nil
+}
+
+// ExecuteTemplate runs an SVG template without parameters, producing the
+// document it renders when none are given, e.g. to lint it
+func ExecuteTemplate(name string, source []byte) ([]byte, error) {
+	return executeTemplate(name, source, nil)
+}
@@ -7,16 +7,11 @@ export PUID=${PUID:-$(id -u)}
 export PGID=${PGID:-$(id -g)}
 export TZ=${TZ:-UTC}
 
-# Create required directories if they don't exist
+# Create required directories if they don't exist. Templates added to
+# static/svg override the stock templates built into the image.
 mkdir -p static/svg
 mkdir -p svg-cache
 
-# Check if the basic-auth.svg exists, if not copy from original location
-if [ ! -f static/svg/basic-auth.svg ]; then
-  echo "Copying default SVG template..."
-  cp svg/basic-auth.svg static/svg/ || echo "Warning: Could not copy default SVG template"
-fi
-
 # Build and start the containers
 echo "Starting SVG Web Elements service..."
 docker compose up -d
@@ -0,0 +1,19 @@
+// Package static holds the stock templates compiled into the binary
+package static
+
+import (
+	"embed"
+	"io/fs"
+)
+
+//go:embed svg
+var files embed.FS
+
+// Templates returns the stock templates, rooted at the template directory
+func Templates() fs.FS {
+	templates, err := fs.Sub(files, "svg")
+	if err != nil {
+		panic(err)
+	}
+	return templates
+}
@@ -0,0 +1,91 @@
+package svgwe
+
+import (
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/svg-web-elements/internal/svg"
+)
+
+// Params holds the customizations for a render. Its methods return the
+// Params so calls can be chained. The zero value is not usable; create
+// Params with NewParams or ParseParams.
+type Params struct {
+	values url.Values
+}
+
+// NewParams returns empty Params
+func NewParams() *Params {
+	return &Params{values: make(url.Values)}
+}
+
+// ParseParams parses Params from a query string as used in /ui/ URLs, e.g.
+// "text.text-title=Login&width=400"
+func ParseParams(query string) (*Params, error) {
+	values, err := url.ParseQuery(strings.TrimPrefix(query, "?"))
+	if err != nil {
+		return nil, err
+	}
+	return &Params{values: values}, nil
+}
+
+// Text replaces the text of an element with plain text, which is escaped
+func (p *Params) Text(elementID, text string) *Params {
+	return p.Set("text."+elementID, text)
+}
+
+// Color changes the fill color of an element
+func (p *Params) Color(elementID, color string) *Params {
+	return p.Set("color."+elementID, color)
+}
+
+// Image fills a rect or image placeholder with an image from the assets
+// directory or an allowed URL
+func (p *Params) Image(elementID, source string) *Params {
+	return p.Set("image."+elementID, source)
+}
+
+// Fit sets how an image fills its placeholder: cover, contain or fill
+func (p *Params) Fit(elementID, fit string) *Params {
+	return p.Set("fit."+elementID, fit)
+}
+
+// Width sets the rendered width. The height scales proportionally unless
+// it is set too.
+func (p *Params) Width(width int) *Params {
+	return p.Set("width", strconv.Itoa(width))
+}
+
+// Height sets the rendered height. The width scales proportionally unless
+// it is set too.
+func (p *Params) Height(height int) *Params {
+	return p.Set("height", strconv.Itoa(height))
+}
+
+// Lang selects the translation catalog
+func (p *Params) Lang(lang string) *Params {
+	return p.Set("lang", lang)
+}
+
+// List sets a list parameter, as used by repeated elements and templates
+func (p *Params) List(key string, items ...string) *Params {
+	return p.Set(key, strings.Join(items, ","))
+}
+
+// Set sets a raw parameter, e.g. "frame.text.text-url" for an included
+// fragment or a parameter read by a Go template
+func (p *Params) Set(key, value string) *Params {
+	p.values.Set(key, value)
+	return p
+}
+
+// Query returns the Params as a query string for a /ui/ URL
+func (p *Params) Query() string {
+	return p.values.Encode()
+}
+
+// svgParams converts the Params for the processor
+func (p *Params) svgParams() (svg.SVGParams, error) {
+	return svg.ParseQuery(p.values)
+}
@@ -0,0 +1,148 @@
+// Package svgwe renders SVG Web Elements templates in-process.
+//
+// A Renderer loads templates from a directory and renders them with Params,
+// exactly as the service does for /ui/ requests:
+//
+//	renderer := svgwe.New("static/svg")
+//	params := svgwe.NewParams().
+//		Text("text-title", "Login").
+//		Color("btn-background_2", "#0ea5e9").
+//		Width(400)
+//	err := renderer.Render(w, "basic-auth.svg", params)
+//
+// The HTTP handler can be mounted under any mux and wrapped in middleware:
+//
+//	mux.Handle("/illustrations/", http.StripPrefix("/illustrations/", renderer.Handler()))
+package svgwe
+
+import (
+	"io"
+	"io/fs"
+	"net/http"
+	"time"
+
+	"github.com/svg-web-elements/internal/handlers"
+	"github.com/svg-web-elements/internal/svg"
+	"github.com/svg-web-elements/static"
+)
+
+// Errors returned by Render and RenderBytes, to be checked with errors.Is
+var (
+	// ErrNotFound is returned for templates that don't exist
+	ErrNotFound = svg.ErrNotFound
+	// ErrInvalidElementID is returned for Params targeting element IDs
+	// that aren't XML names, e.g. Text("a(", "x")
+	ErrInvalidElementID = svg.ErrInvalidElementID
+	// ErrLimitExceeded is returned for Params asking for more than the
+	// renderer's limits allow
+	ErrLimitExceeded = svg.ErrLimitExceeded
+)
+
+// Renderer renders templates from a template directory
+type Renderer struct {
+	processor *svg.Processor
+}
+
+// Option configures a Renderer
+type Option func(*Renderer)
+
+// WithAssets sets the directory images for Params.Image are read from
+func WithAssets(dir string) Option {
+	return func(r *Renderer) {
+		r.processor.AssetsPath = dir
+	}
+}
+
+// WithRemoteImages allows Params.Image to fetch images from URLs on the
+// given hosts, within a size and time limit
+func WithRemoteImages(hosts []string, maxBytes int64, timeout time.Duration) Option {
+	return func(r *Renderer) {
+		r.processor.RemoteImages = &svg.RemoteImagePolicy{
+			AllowedHosts: hosts,
+			MaxBytes:     maxBytes,
+			Timeout:      timeout,
+		}
+	}
+}
+
+// WithLimits sets the caps on what a single render may ask for: the largest
+// width or height, the longest parameter value and output in bytes, and the
+// most parameters. Zero disables a cap. Renderers start with the service's
+// defaults.
+func WithLimits(maxDimension float64, maxTextLength, maxParams, maxOutputBytes int) Option {
+	return func(r *Renderer) {
+		r.processor.Limits = svg.RenderLimits{
+			MaxDimension:   maxDimension,
+			MaxTextLength:  maxTextLength,
+			MaxParams:      maxParams,
+			MaxOutputBytes: maxOutputBytes,
+		}
+	}
+}
+
+// New creates a Renderer for the templates in dir
+func New(dir string, opts ...Option) *Renderer {
+	return newRenderer(svg.NewProcessor(dir), opts)
+}
+
+// NewFS creates a Renderer for the templates in fsys, such as an embed.FS
+// or a combination made with Overlay
+func NewFS(fsys fs.FS, opts ...Option) *Renderer {
+	return newRenderer(svg.NewProcessorFS(fsys), opts)
+}
+
+// DefaultTemplates returns the stock templates that ship with the service
+func DefaultTemplates() fs.FS {
+	return static.Templates()
+}
+
+// Overlay combines template sources. Templates in earlier sources replace
+// those of the same name in later ones, e.g.
+// Overlay(os.DirFS("templates"), DefaultTemplates()).
+func Overlay(sources ...fs.FS) fs.FS {
+	return svg.OverlayFS(sources...)
+}
+
+// newRenderer applies the options to a renderer for the processor
+func newRenderer(processor *svg.Processor, opts []Option) *Renderer {
+	r := &Renderer{processor: processor}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// Templates returns the names of the available templates
+func (r *Renderer) Templates() ([]string, error) {
+	return r.processor.ListAvailableSVGs()
+}
+
+// RenderBytes renders a template and returns the SVG document. Params
+// targeting invalid element IDs fail with ErrInvalidElementID.
+func (r *Renderer) RenderBytes(name string, params *Params) ([]byte, error) {
+	if params == nil {
+		params = NewParams()
+	}
+	svgParams, err := params.svgParams()
+	if err != nil {
+		return nil, err
+	}
+	return r.processor.ProcessSVG(name, svgParams)
+}
+
+// Render renders a template and writes the SVG document to w
+func (r *Renderer) Render(w io.Writer, name string, params *Params) error {
+	data, err := r.RenderBytes(name, params)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+// Handler returns an http.Handler serving templates by name with query
+// parameters, like the service's /ui/ endpoint. Mount it with
+// http.StripPrefix so the request path is the template name.
+func (r *Renderer) Handler() http.Handler {
+	return handlers.NewSVGHandler(r.processor)
+}